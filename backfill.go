@@ -0,0 +1,179 @@
+package gormigrate
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultBackfillKeyColumn = "id"
+	defaultBackfillBatchSize = 1000
+)
+
+// BackfillBatchFunc is the func signature for processing a batch of a backfill.
+// It must process the rows whose key is between first and last, both included.
+type BackfillBatchFunc func(tx *gorm.DB, first, last int64) error
+
+// BackfillProgress describes the progress of a backfill, after a batch.
+type BackfillProgress struct {
+	// ID is the identifier of the backfill migration.
+	ID string
+	// Batches is the number of batches processed by this run.
+	Batches int
+	// Rows is the number of rows processed, including previous interrupted runs.
+	Rows int64
+	// LastKey is the key of the last processed row.
+	LastKey int64
+}
+
+// Backfill describes a large data change processed in batches of rows, ordered
+// by an integer key. Each batch runs in its own transaction, together with
+// saving a checkpoint, so that an interrupted backfill resumes after the last
+// processed batch instead of restarting.
+type Backfill struct {
+	// Table is the table whose rows are processed.
+	Table string
+	// KeyColumn is the unique integer column rows are ordered by. Defaults to "id".
+	KeyColumn string
+	// BatchSize is the number of rows of each batch. Defaults to 1000.
+	BatchSize int
+	// Pause is the time to wait between two batches.
+	Pause time.Duration
+	// Batch processes a batch of rows.
+	Batch BackfillBatchFunc
+	// Progress is called after each batch. Can be nil.
+	Progress func(BackfillProgress)
+}
+
+// checkpointRecord is a row of the table set by Options.CheckpointTableName.
+type checkpointRecord struct {
+	MigrationID   string `gorm:"primaryKey;size:255"`
+	LastKey       int64
+	ProcessedRows int64
+	UpdatedAt     time.Time
+}
+
+// runBackfill processes the batches of the backfill of m that were not processed yet.
+// Batches are committed on their own, even when Options.UseTransaction is set.
+func (g *Gormigrate) runBackfill(m *Migration, tx *gorm.DB) error {
+	b := m.Backfill
+	if b.Table == "" || b.Batch == nil {
+		return ErrInvalidBackfill
+	}
+	keyColumn := b.KeyColumn
+	if keyColumn == "" {
+		keyColumn = defaultBackfillKeyColumn
+	}
+	batchSize := b.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBackfillBatchSize
+	}
+
+	if g.options.UseTransaction {
+		// The batches could not see the uncommitted changes of the previous
+		// migrations, and would wait for their locks: these are committed
+		// first, and the transaction of the run starts again after the batches
+		if err := g.commit(); err != nil {
+			return err
+		}
+		g.tx, g.state = g.db, g.stateDatabase()
+	}
+
+	ctx := tx.Statement.Context
+	db := g.db.WithContext(ctx)
+	// Checkpoints are saved in the same transaction as their batch, unless
+	// they are kept in a separate state database
+	stateDB := g.stateDatabase().WithContext(ctx)
+	if !stateDB.Migrator().HasTable(g.options.CheckpointTableName) {
+		if err := stateDB.Table(g.options.CheckpointTableName).AutoMigrate(&checkpointRecord{}); err != nil {
+			return err
+		}
+	}
+
+	var checkpoints []*checkpointRecord
+	if err := stateDB.Table(g.options.CheckpointTableName).Where("migration_id = ?", m.ID).Find(&checkpoints).Error; err != nil {
+		return err
+	}
+	checkpoint := &checkpointRecord{MigrationID: m.ID}
+	if len(checkpoints) > 0 {
+		checkpoint = checkpoints[0]
+	}
+	resume := len(checkpoints) > 0
+
+	for batches := 1; ; batches++ {
+		var keys []int64
+		query := db.Table(b.Table).Order(clause.OrderByColumn{Column: clause.Column{Name: keyColumn}}).Limit(batchSize)
+		if resume {
+			query = query.Where(clause.Gt{Column: clause.Column{Name: keyColumn}, Value: checkpoint.LastKey})
+		}
+		if err := query.Pluck(keyColumn, &keys).Error; err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			break
+		}
+
+		err := db.Transaction(func(batchTx *gorm.DB) error {
+			if err := b.Batch(batchTx, keys[0], keys[len(keys)-1]); err != nil {
+				return err
+			}
+			checkpoint.LastKey = keys[len(keys)-1]
+			checkpoint.ProcessedRows += int64(len(keys))
+			if g.stateDB != nil {
+				return nil
+			}
+			return g.saveCheckpoint(batchTx, checkpoint)
+		})
+		if err == nil && g.stateDB != nil {
+			err = g.saveCheckpoint(stateDB, checkpoint)
+		}
+		if err != nil {
+			return err
+		}
+		resume = true
+
+		if b.Progress != nil {
+			b.Progress(BackfillProgress{ID: m.ID, Batches: batches, Rows: checkpoint.ProcessedRows, LastKey: checkpoint.LastKey})
+		}
+		if len(keys) < batchSize {
+			break
+		}
+		if err := sleep(ctx, b.Pause); err != nil {
+			return err
+		}
+	}
+
+	if g.options.UseTransaction {
+		g.beginTransaction()
+	}
+	// The checkpoint is removed together with the migration being recorded as applied
+	return g.state.Table(g.options.CheckpointTableName).Where("migration_id = ?", m.ID).Delete(&checkpointRecord{}).Error
+}
+
+func (g *Gormigrate) saveCheckpoint(tx *gorm.DB, checkpoint *checkpointRecord) error {
+	return tx.
+		Table(g.options.CheckpointTableName).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "migration_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"last_key", "processed_rows", "updated_at"}),
+		}).
+		Create(checkpoint).
+		Error
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,175 @@
+package gormigrate
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	captureBeforeCallback = "gormigrate:capture_before"
+	captureAfterCallback  = "gormigrate:capture_after"
+	captureStartKey       = "gormigrate:capture_start"
+)
+
+// captureKey is the context key holding the *statementCapture of the running migration.
+type captureKey struct{}
+
+type statementCapture struct {
+	statements []*Statement
+}
+
+// statementRecord is a row of the table set by Options.StatementsTableName.
+type statementRecord struct {
+	ID          uint `gorm:"primaryKey"`
+	MigrationID string
+	Step        string
+	Statement   string
+	Duration    int64
+	CreatedAt   time.Time
+}
+
+// registerCaptureCallbacks registers the callbacks capturing the statements
+// executed by migrations, unless already registered. The callbacks only act
+// on statements whose context holds a *statementCapture, so other users of db
+// are not affected.
+func registerCaptureCallbacks(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if callbacks.Raw().Get(captureAfterCallback) != nil {
+		return nil
+	}
+	for _, err := range []error{
+		callbacks.Create().Before("*").Register(captureBeforeCallback, captureBefore),
+		callbacks.Create().After("*").Register(captureAfterCallback, captureAfter),
+		callbacks.Query().Before("*").Register(captureBeforeCallback, captureBefore),
+		callbacks.Query().After("*").Register(captureAfterCallback, captureAfter),
+		callbacks.Update().Before("*").Register(captureBeforeCallback, captureBefore),
+		callbacks.Update().After("*").Register(captureAfterCallback, captureAfter),
+		callbacks.Delete().Before("*").Register(captureBeforeCallback, captureBefore),
+		callbacks.Delete().After("*").Register(captureAfterCallback, captureAfter),
+		callbacks.Row().Before("*").Register(captureBeforeCallback, captureBefore),
+		callbacks.Row().After("*").Register(captureAfterCallback, captureAfter),
+		callbacks.Raw().Before("*").Register(captureBeforeCallback, captureBefore),
+		callbacks.Raw().After("*").Register(captureAfterCallback, captureAfter),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func captureFromContext(db *gorm.DB) *statementCapture {
+	if db.Statement.Context == nil {
+		return nil
+	}
+	capture, _ := db.Statement.Context.Value(captureKey{}).(*statementCapture)
+	return capture
+}
+
+func captureBefore(db *gorm.DB) {
+	if captureFromContext(db) != nil {
+		db.InstanceSet(captureStartKey, time.Now())
+	}
+}
+
+func captureAfter(db *gorm.DB) {
+	capture := captureFromContext(db)
+	if capture == nil || db.Statement.SQL.Len() == 0 {
+		return
+	}
+	statement := &Statement{
+		SQL:          db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...),
+		RowsAffected: db.RowsAffected,
+		Err:          db.Error,
+	}
+	if start, ok := db.InstanceGet(captureStartKey); ok {
+		statement.Duration = time.Since(start.(time.Time))
+	}
+	capture.statements = append(capture.statements, statement)
+}
+
+// run executes fn, the function of the given step of the migration with the
+// given ID, and adds the outcome to the run report.
+func (g *Gormigrate) run(id string, step Step, fn func(*gorm.DB) error) error {
+	report := &MigrationReport{ID: id, Step: step}
+	g.addReport(report)
+
+	ctx, span := g.startSpan(contextOf(g.tx), "gormigrate.Migration",
+		MigrationIDKey.String(id), StepKey.String(string(step)), dbSystem(g.tx))
+	tx := g.tx
+	if g.options.TracerProvider != nil {
+		tx = tx.WithContext(ctx)
+	}
+	var capture *statementCapture
+	if g.options.CaptureStatements {
+		if g.captureErr != nil {
+			return g.captureErr
+		}
+		capture = &statementCapture{}
+		tx = tx.WithContext(context.WithValue(tx.Statement.Context, captureKey{}, capture))
+	}
+
+	start := time.Now()
+	err := fn(tx)
+	report.Duration = time.Since(start)
+	report.Err = err
+	endSpan(span, err)
+	g.observeMigration(report)
+	if capture != nil {
+		report.Statements = capture.statements
+	}
+	if err != nil {
+		return err
+	}
+	return g.storeStatements(report)
+}
+
+// storeStatements saves the statements of the report in the table set by
+// Options.StatementsTableName, creating the table if needed.
+func (g *Gormigrate) storeStatements(report *MigrationReport) error {
+	if g.options.StatementsTableName == "" || len(report.Statements) == 0 {
+		return nil
+	}
+	if !g.state.Migrator().HasTable(g.options.StatementsTableName) {
+		if err := g.state.Table(g.options.StatementsTableName).AutoMigrate(&statementRecord{}); err != nil {
+			return err
+		}
+	}
+
+	records := make([]*statementRecord, 0, len(report.Statements))
+	for _, statement := range report.Statements {
+		records = append(records, &statementRecord{
+			MigrationID: report.ID,
+			Step:        string(report.Step),
+			Statement:   statement.SQL,
+			Duration:    int64(statement.Duration),
+		})
+	}
+	return g.state.Table(g.options.StatementsTableName).Create(&records).Error
+}
+
+// ExportStatements writes the statements stored in the table set by
+// Options.StatementsTableName to w, in the order they were executed, as an SQL
+// script that can be replayed against another database.
+func (g *Gormigrate) ExportStatements(w io.Writer) error {
+	if g.options.StatementsTableName == "" {
+		return ErrNoStatementsTable
+	}
+
+	var records []*statementRecord
+	if err := g.stateDatabase().Table(g.options.StatementsTableName).Order("id").Find(&records).Error; err != nil {
+		return err
+	}
+
+	var lines []string
+	for i, record := range records {
+		if i == 0 || record.MigrationID != records[i-1].MigrationID || record.Step != records[i-1].Step {
+			lines = append(lines, migrationComment(record.MigrationID, Step(record.Step)))
+		}
+		lines = append(lines, record.Statement)
+	}
+	return writeSQL(w, lines)
+}
@@ -0,0 +1,67 @@
+package gormigrate
+
+// CheckOptions choose the conditions that do not make Check fail. The zero
+// value makes all of them fail.
+type CheckOptions struct {
+	// AllowMissingTable accepts a database without migration table, whose
+	// migrations are then all pending.
+	AllowMissingTable bool
+	// AllowPending accepts migrations that did not run yet, e.g. when the
+	// application is deployed before migrating.
+	AllowPending bool
+	// AllowUnknown accepts applied migrations that do not exist in the code,
+	// e.g. when an older version of the application runs during a deploy.
+	AllowUnknown bool
+}
+
+// Check verifies that the database is compatible with the migrations, for
+// application servers that do not migrate the database themselves but should
+// refuse to start otherwise. It returns ErrMissingMigrationTable, an
+// *UnknownMigrationsError or a *PendingMigrationsError, unless allowed by
+// options, which can be nil.
+//
+// Migrations whose precondition currently fails are pending as well, since
+// they did not run. Like Status, it only reads from the database: it does not create the
+// migration table nor take the lock of runs.
+func (g *Gormigrate) Check(options *CheckOptions) error {
+	if options == nil {
+		options = &CheckOptions{}
+	}
+	g.tx = g.db
+	g.state = g.stateDatabase()
+	hasTable, err := g.stateStore().Exists(g.state)
+	if err != nil {
+		return err
+	}
+	if !hasTable && !options.AllowMissingTable {
+		return ErrMissingMigrationTable
+	}
+
+	if hasTable && !options.AllowUnknown {
+		unknownIDs, err := g.unknownMigrationIDs()
+		if err != nil {
+			return err
+		}
+		if len(unknownIDs) > 0 {
+			return &UnknownMigrationsError{IDs: unknownIDs}
+		}
+	}
+
+	if options.AllowPending {
+		return nil
+	}
+	statuses, err := g.Status()
+	if err != nil {
+		return err
+	}
+	var pendingIDs []string
+	for _, status := range statuses {
+		if status.State.pending() {
+			pendingIDs = append(pendingIDs, status.ID)
+		}
+	}
+	if len(pendingIDs) > 0 {
+		return &PendingMigrationsError{IDs: pendingIDs}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Command gormigrate creates migration files.
+//
+// Usage:
+//
+//	gormigrate new [flags] <name>
+//
+// The new command creates a Go file registering a migration with stub Migrate
+// and Rollback functions, or up and down SQL files with -sql. See
+// gormigrate.Scaffold.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "new" {
+		fmt.Fprintln(os.Stderr, "usage: gormigrate new [flags] <name>")
+		os.Exit(2)
+	}
+
+	var (
+		options gormigrate.ScaffoldOptions
+		format  string
+	)
+	flags := flag.NewFlagSet("new", flag.ExitOnError)
+	flags.StringVar(&options.Dir, "dir", "migrations", "directory of the migrations package")
+	flags.StringVar(&options.Package, "package", "", "package name, defaults to the base name of -dir")
+	flags.StringVar(&options.ID, "id", "", "migration ID, defaults to the next ID in -format")
+	flags.StringVar(&format, "format", string(gormigrate.IDFormatTimestamp), "ID format: timestamp, sequence or semver")
+	flags.IntVar(&options.IDColumnSize, "id-size", gormigrate.DefaultOptions.IDColumnSize, "maximum ID length")
+	flags.BoolVar(&options.SQL, "sql", false, "create up and down SQL files instead of a Go file")
+	flags.StringVar(&options.Registry, "registry", "Migrations", "name of the package variable holding the registry")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gormigrate new [flags] <name>")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(os.Args[2:])
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	options.Name = flags.Arg(0)
+	options.IDFormat = gormigrate.IDFormat(format)
+
+	paths, err := gormigrate.Scaffold(&options)
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,26 @@
+package gormigrate
+
+// migrateFunc returns the Migrate function of the migration for the given dialect.
+func (m *Migration) migrateFunc(dialect string) (MigrateFunc, error) {
+	if fn, ok := m.DialectMigrate[dialect]; ok {
+		return fn, nil
+	}
+	if m.Migrate == nil {
+		return nil, &UnsupportedDialectError{ID: m.ID, Step: StepMigrate, Dialect: dialect}
+	}
+	return m.Migrate, nil
+}
+
+// rollbackFunc returns the Rollback function of the migration for the given dialect.
+func (m *Migration) rollbackFunc(dialect string) (RollbackFunc, error) {
+	if fn, ok := m.DialectRollback[dialect]; ok {
+		return fn, nil
+	}
+	if m.Rollback != nil {
+		return m.Rollback, nil
+	}
+	if len(m.DialectRollback) > 0 {
+		return nil, &UnsupportedDialectError{ID: m.ID, Step: StepRollback, Dialect: dialect}
+	}
+	return nil, ErrRollbackImpossible
+}
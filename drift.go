@@ -0,0 +1,233 @@
+package gormigrate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// DriftKind is the kind of a difference between a model and the database.
+type DriftKind string
+
+const (
+	// DriftMissingTable means the table of the model does not exist.
+	DriftMissingTable DriftKind = "missing-table"
+	// DriftMissingColumn means a field of the model has no column.
+	DriftMissingColumn DriftKind = "missing-column"
+	// DriftExtraColumn means a column has no field in the model.
+	DriftExtraColumn DriftKind = "extra-column"
+	// DriftMismatchedColumn means a column type or nullability differs from its field.
+	DriftMismatchedColumn DriftKind = "mismatched-column"
+	// DriftMissingIndex means an index of the model does not exist.
+	DriftMissingIndex DriftKind = "missing-index"
+	// DriftExtraIndex means an index is not defined by the model.
+	DriftExtraIndex DriftKind = "extra-index"
+	// DriftMismatchedIndex means the columns or uniqueness of an index differ from the model.
+	DriftMismatchedIndex DriftKind = "mismatched-index"
+)
+
+// Drift is a difference between a model and the database.
+type Drift struct {
+	// Kind is the kind of difference.
+	Kind DriftKind
+	// Table is the table of the model.
+	Table string
+	// Name is the name of the column or index. Empty for missing tables.
+	Name string
+	// Expected describes the column or index as defined by the model.
+	Expected string
+	// Actual describes the column or index found in the database.
+	Actual string
+}
+
+func (d *Drift) String() string {
+	switch d.Kind {
+	case DriftMissingTable:
+		return fmt.Sprintf(`%s: missing table`, d.Table)
+	case DriftMismatchedColumn, DriftMismatchedIndex:
+		return fmt.Sprintf(`%s: %s "%s": expected %s, got %s`, d.Table, d.Kind, d.Name, d.Expected, d.Actual)
+	default:
+		return fmt.Sprintf(`%s: %s "%s"`, d.Table, d.Kind, d.Name)
+	}
+}
+
+// CheckDrift compares the given models with the database, usually after
+// running Migrate, and returns the differences: missing, extra or mismatched
+// columns and indexes. Models are gorm model structs, e.g. &User{}.
+//
+// Column types are compared like AutoMigrate does, so an empty result means
+// AutoMigrate would not change the tables of the models.
+func CheckDrift(db *gorm.DB, models ...any) ([]*Drift, error) {
+	var drifts []*Drift
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		modelDrifts, err := checkTableDrift(db, model, stmt.Schema)
+		if err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, modelDrifts...)
+	}
+	return drifts, nil
+}
+
+func checkTableDrift(db *gorm.DB, model any, s *schema.Schema) ([]*Drift, error) {
+	migrator := db.Migrator()
+	if !migrator.HasTable(model) {
+		return []*Drift{{Kind: DriftMissingTable, Table: s.Table}}, nil
+	}
+
+	columnTypes, err := migrator.ColumnTypes(model)
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]gorm.ColumnType, len(columnTypes))
+	for _, columnType := range columnTypes {
+		columns[columnType.Name()] = columnType
+	}
+
+	var drifts []*Drift
+	fields := make(map[string]*schema.Field)
+	for _, dbName := range s.DBNames {
+		field := s.FieldsByDBName[dbName]
+		if field.IgnoreMigration {
+			continue
+		}
+		fields[dbName] = field
+		columnType, ok := columns[dbName]
+		if !ok {
+			drifts = append(drifts, &Drift{Kind: DriftMissingColumn, Table: s.Table, Name: dbName})
+			continue
+		}
+		if !columnMatches(migrator, field, columnType) {
+			drifts = append(drifts, &Drift{
+				Kind:     DriftMismatchedColumn,
+				Table:    s.Table,
+				Name:     dbName,
+				Expected: describeField(migrator, field),
+				Actual:   describeColumn(columnType),
+			})
+		}
+	}
+	for _, columnType := range columnTypes {
+		if _, ok := fields[columnType.Name()]; !ok {
+			drifts = append(drifts, &Drift{Kind: DriftExtraColumn, Table: s.Table, Name: columnType.Name()})
+		}
+	}
+
+	indexDrifts, err := checkIndexDrift(migrator, model, s)
+	if err != nil {
+		return nil, err
+	}
+	return append(drifts, indexDrifts...), nil
+}
+
+func checkIndexDrift(migrator gorm.Migrator, model any, s *schema.Schema) ([]*Drift, error) {
+	indexes, err := migrator.GetIndexes(model)
+	if err != nil {
+		return nil, err
+	}
+	actual := make(map[string]string, len(indexes))
+	for _, index := range indexes {
+		if primaryKey, _ := index.PrimaryKey(); primaryKey {
+			continue
+		}
+		unique, _ := index.Unique()
+		// Unique constraints of fields are reported as indexes by some dialects
+		if columns := index.Columns(); unique && len(columns) == 1 {
+			if field := s.LookUpField(columns[0]); field != nil && field.Unique {
+				continue
+			}
+		}
+		actual[index.Name()] = describeIndex(index.Columns(), unique)
+	}
+
+	var drifts []*Drift
+	expected := make(map[string]bool)
+	for _, index := range s.ParseIndexes() {
+		expected[index.Name] = true
+		columns := make([]string, 0, len(index.Fields))
+		for _, field := range index.Fields {
+			columns = append(columns, field.DBName)
+		}
+		description := describeIndex(columns, index.Class == "UNIQUE")
+		switch actualDescription, ok := actual[index.Name]; {
+		case !ok:
+			drifts = append(drifts, &Drift{Kind: DriftMissingIndex, Table: s.Table, Name: index.Name})
+		case actualDescription != description:
+			drifts = append(drifts, &Drift{
+				Kind:     DriftMismatchedIndex,
+				Table:    s.Table,
+				Name:     index.Name,
+				Expected: description,
+				Actual:   actualDescription,
+			})
+		}
+	}
+
+	var extra []string
+	for name := range actual {
+		if !expected[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		drifts = append(drifts, &Drift{Kind: DriftExtraIndex, Table: s.Table, Name: name})
+	}
+	return drifts, nil
+}
+
+// columnMatches reports whether the column type and nullability match the
+// field, following the checks of AutoMigrate.
+func columnMatches(migrator gorm.Migrator, field *schema.Field, columnType gorm.ColumnType) bool {
+	if field.PrimaryKey {
+		return true
+	}
+	if nullable, ok := columnType.Nullable(); ok && nullable == field.NotNull {
+		return false
+	}
+	if length, ok := columnType.Length(); ok && length > 0 && field.Size > 0 && length != int64(field.Size) {
+		return false
+	}
+
+	expected := strings.ToLower(strings.TrimSpace(migrator.FullDataTypeOf(field).SQL))
+	actual := strings.ToLower(columnType.DatabaseTypeName())
+	if strings.HasPrefix(expected, actual) {
+		return true
+	}
+	for _, alias := range migrator.GetTypeAliases(actual) {
+		if strings.HasPrefix(expected, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+func describeField(migrator gorm.Migrator, field *schema.Field) string {
+	return strings.TrimSpace(migrator.FullDataTypeOf(field).SQL)
+}
+
+func describeColumn(columnType gorm.ColumnType) string {
+	description, ok := columnType.ColumnType()
+	if !ok || description == "" {
+		description = columnType.DatabaseTypeName()
+	}
+	if nullable, ok := columnType.Nullable(); ok && !nullable {
+		description += " NOT NULL"
+	}
+	return description
+}
+
+func describeIndex(columns []string, unique bool) string {
+	description := "(" + strings.Join(columns, ", ") + ")"
+	if unique {
+		description = "UNIQUE " + description
+	}
+	return description
+}
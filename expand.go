@@ -0,0 +1,347 @@
+package gormigrate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ColumnChange describes renaming a column, or changing its type, without
+// downtime, following the expand/contract pattern: the new column is added
+// next to the existing one and kept in sync by triggers while both old and
+// new code run, then the existing column is dropped once no code uses it.
+// Triggers are supported on postgres, mysql and sqlite.
+//
+// On sqlite the triggers run after the row is written, so Column must accept
+// NULL for rows inserted by code only writing NewColumn.
+type ColumnChange struct {
+	// Table is the table of the column.
+	Table string
+	// Column is the existing column.
+	Column string
+	// Type is the SQL type of Column, e.g. "varchar(255)". It is used to add
+	// Column back when rolling back the contract migration.
+	Type string
+	// NewColumn is the column replacing Column.
+	NewColumn string
+	// NewType is the SQL type of NewColumn. Defaults to Type.
+	NewType string
+	// ToNew is an SQL expression converting a value of Column to NewType, where
+	// %s stands for the value, e.g. "CAST(%s AS bigint)". Defaults to the value itself.
+	ToNew string
+	// ToOld is an SQL expression converting a value of NewColumn to Type, where
+	// %s stands for the value. Defaults to the value itself.
+	ToOld string
+	// KeyColumn is the unique integer column the backfill orders rows by. Defaults to "id".
+	KeyColumn string
+	// BatchSize is the number of rows of each batch of the backfill. Defaults to 1000.
+	BatchSize int
+}
+
+// columnSyncTriggers returns, for each supported dialect, the statements
+// creating and dropping the triggers keeping both columns of a change in sync.
+var columnSyncTriggers = map[string]func(c *ColumnChange, quote func(string) string) (create, drop []string){
+	"postgres": postgresColumnSyncTriggers,
+	"mysql":    mysqlColumnSyncTriggers,
+	"sqlite":   sqliteColumnSyncTriggers,
+}
+
+// Migrations returns the migrations applying the change, identified by id
+// followed by a suffix:
+//   - id+"_expand" adds NewColumn and installs the triggers copying writes of
+//     either column to the other one;
+//   - id+"_backfill" copies Column to NewColumn for the existing rows, in
+//     batches, see Backfill;
+//   - id+"_contract" is a post-deploy migration dropping the triggers and Column.
+//
+// The contract migration can be left out of the migration list until the
+// release after the one switching the code to NewColumn.
+//
+// The backfill batches must see NewColumn, and must not wait for the lock the
+// expand migration holds on Table: with Options.UseTransaction, the expand
+// migration, and the ones before it, are committed before the backfill runs.
+func (c *ColumnChange) Migrations(id string) []*Migration {
+	expand := &Migration{
+		ID:              id + "_expand",
+		DialectMigrate:  make(map[string]MigrateFunc),
+		DialectRollback: make(map[string]RollbackFunc),
+	}
+	contract := &Migration{
+		ID:              id + "_contract",
+		DialectMigrate:  make(map[string]MigrateFunc),
+		DialectRollback: make(map[string]RollbackFunc),
+		Phase:           PhasePostDeploy,
+	}
+	for dialect := range columnSyncTriggers {
+		expand.DialectMigrate[dialect] = c.expand
+		expand.DialectRollback[dialect] = c.rollbackExpand
+		contract.DialectMigrate[dialect] = c.contract
+		contract.DialectRollback[dialect] = c.rollbackContract
+	}
+
+	backfill := &Migration{
+		ID: id + "_backfill",
+		Backfill: &Backfill{
+			Table:     c.Table,
+			KeyColumn: c.keyColumn(),
+			BatchSize: c.BatchSize,
+			Batch:     c.backfillBatch,
+		},
+		// The copied values are dropped together with NewColumn by the
+		// rollback of the expand migration.
+		Rollback: func(*gorm.DB) error { return nil },
+	}
+	return []*Migration{expand, backfill, contract}
+}
+
+func (c *ColumnChange) expand(tx *gorm.DB) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if !tx.Migrator().HasColumn(c.Table, c.NewColumn) {
+		if err := c.addColumn(tx, c.NewColumn, c.newType()); err != nil {
+			return err
+		}
+	}
+	return c.createTriggers(tx)
+}
+
+func (c *ColumnChange) rollbackExpand(tx *gorm.DB) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if err := c.dropTriggers(tx); err != nil {
+		return err
+	}
+	if !tx.Migrator().HasColumn(c.Table, c.NewColumn) {
+		return nil
+	}
+	return c.dropColumn(tx, c.NewColumn)
+}
+
+func (c *ColumnChange) backfillBatch(tx *gorm.DB, first, last int64) error {
+	sql := fmt.Sprintf(
+		"UPDATE %s SET %s = %s WHERE %s BETWEEN ? AND ?",
+		quote(tx, c.Table), quote(tx, c.NewColumn), convert(c.ToNew, quote(tx, c.Column)), quote(tx, c.keyColumn()),
+	)
+	return tx.Exec(sql, first, last).Error
+}
+
+func (c *ColumnChange) contract(tx *gorm.DB) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if err := c.dropTriggers(tx); err != nil {
+		return err
+	}
+	if !tx.Migrator().HasColumn(c.Table, c.Column) {
+		return nil
+	}
+	return c.dropColumn(tx, c.Column)
+}
+
+func (c *ColumnChange) rollbackContract(tx *gorm.DB) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if !tx.Migrator().HasColumn(c.Table, c.Column) {
+		if err := c.addColumn(tx, c.Column, c.Type); err != nil {
+			return err
+		}
+	}
+	sql := fmt.Sprintf(
+		"UPDATE %s SET %s = %s",
+		quote(tx, c.Table), quote(tx, c.Column), convert(c.ToOld, quote(tx, c.NewColumn)),
+	)
+	if err := tx.Exec(sql).Error; err != nil {
+		return err
+	}
+	return c.createTriggers(tx)
+}
+
+func (c *ColumnChange) createTriggers(tx *gorm.DB) error {
+	if err := c.dropTriggers(tx); err != nil {
+		return err
+	}
+	create, _ := columnSyncTriggers[tx.Dialector.Name()](c, func(name string) string { return quote(tx, name) })
+	return execAll(tx, create)
+}
+
+func (c *ColumnChange) dropTriggers(tx *gorm.DB) error {
+	_, drop := columnSyncTriggers[tx.Dialector.Name()](c, func(name string) string { return quote(tx, name) })
+	return execAll(tx, drop)
+}
+
+func (c *ColumnChange) addColumn(tx *gorm.DB, column, columnType string) error {
+	return tx.Table(c.Table).Migrator().AddColumn(columnModel(column, columnType), column)
+}
+
+func (c *ColumnChange) dropColumn(tx *gorm.DB, column string) error {
+	return tx.Table(c.Table).Migrator().DropColumn(columnModel(column, c.Type), column)
+}
+
+// columnModel returns a model with the single column, for the Migrator to
+// add or drop it.
+func columnModel(column, columnType string) any {
+	f := reflect.StructField{
+		Name: "Column",
+		Type: reflect.TypeOf(""),
+		Tag:  reflect.StructTag(fmt.Sprintf(`gorm:"column:%s;type:%s"`, column, columnType)),
+	}
+	return reflect.New(reflect.StructOf([]reflect.StructField{f})).Interface()
+}
+
+func (c *ColumnChange) validate() error {
+	if c.Table == "" || c.Column == "" || c.NewColumn == "" || c.Type == "" {
+		return ErrInvalidColumnChange
+	}
+	return nil
+}
+
+func (c *ColumnChange) newType() string {
+	if c.NewType == "" {
+		return c.Type
+	}
+	return c.NewType
+}
+
+func (c *ColumnChange) keyColumn() string {
+	if c.KeyColumn == "" {
+		return defaultBackfillKeyColumn
+	}
+	return c.KeyColumn
+}
+
+// triggerName returns the base name of the triggers of the change.
+func (c *ColumnChange) triggerName() string {
+	return c.Table + "_" + c.NewColumn + "_sync"
+}
+
+func postgresColumnSyncTriggers(c *ColumnChange, q func(string) string) (create, drop []string) {
+	name := q(c.triggerName())
+	oldColumn, newColumn := "NEW."+q(c.Column), "NEW."+q(c.NewColumn)
+	create = []string{
+		fmt.Sprintf(`CREATE FUNCTION %s() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    IF %s IS NULL THEN
+      %s := %s;
+    ELSIF %s IS NULL THEN
+      %s := %s;
+    END IF;
+  ELSIF %s IS DISTINCT FROM OLD.%s THEN
+    %s := %s;
+  ELSIF %s IS DISTINCT FROM %s THEN
+    %s := %s;
+  END IF;
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql`,
+			name,
+			newColumn, newColumn, convert(c.ToNew, oldColumn),
+			oldColumn, oldColumn, convert(c.ToOld, newColumn),
+			oldColumn, q(c.Column), newColumn, convert(c.ToNew, oldColumn),
+			newColumn, convert(c.ToNew, oldColumn), oldColumn, convert(c.ToOld, newColumn),
+		),
+		fmt.Sprintf("CREATE TRIGGER %s BEFORE INSERT OR UPDATE ON %s FOR EACH ROW EXECUTE PROCEDURE %s()", name, q(c.Table), name),
+	}
+	drop = []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", name, q(c.Table)),
+		fmt.Sprintf("DROP FUNCTION IF EXISTS %s()", name),
+	}
+	return create, drop
+}
+
+func mysqlColumnSyncTriggers(c *ColumnChange, q func(string) string) (create, drop []string) {
+	insertName, updateName := q(c.triggerName()+"_insert"), q(c.triggerName()+"_update")
+	oldColumn, newColumn := "NEW."+q(c.Column), "NEW."+q(c.NewColumn)
+	create = []string{
+		fmt.Sprintf(`CREATE TRIGGER %s BEFORE INSERT ON %s FOR EACH ROW
+BEGIN
+  IF %s IS NULL THEN
+    SET %s = %s;
+  ELSEIF %s IS NULL THEN
+    SET %s = %s;
+  END IF;
+END`,
+			insertName, q(c.Table),
+			newColumn, newColumn, convert(c.ToNew, oldColumn),
+			oldColumn, oldColumn, convert(c.ToOld, newColumn),
+		),
+		fmt.Sprintf(`CREATE TRIGGER %s BEFORE UPDATE ON %s FOR EACH ROW
+BEGIN
+  IF NOT (%s <=> OLD.%s) THEN
+    SET %s = %s;
+  ELSEIF NOT (%s <=> %s) THEN
+    SET %s = %s;
+  END IF;
+END`,
+			updateName, q(c.Table),
+			oldColumn, q(c.Column), newColumn, convert(c.ToNew, oldColumn),
+			newColumn, convert(c.ToNew, oldColumn), oldColumn, convert(c.ToOld, newColumn),
+		),
+	}
+	drop = []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s", insertName),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s", updateName),
+	}
+	return create, drop
+}
+
+func sqliteColumnSyncTriggers(c *ColumnChange, q func(string) string) (create, drop []string) {
+	insertName := q(c.triggerName() + "_insert")
+	updateOldName, updateNewName := q(c.triggerName()+"_update_old"), q(c.triggerName()+"_update_new")
+	table, column, newColumn := q(c.Table), q(c.Column), q(c.NewColumn)
+	create = []string{
+		fmt.Sprintf(`CREATE TRIGGER %s AFTER INSERT ON %s FOR EACH ROW
+BEGIN
+  UPDATE %s SET %s = COALESCE(NEW.%s, %s), %s = COALESCE(NEW.%s, %s) WHERE rowid = NEW.rowid;
+END`,
+			insertName, table,
+			table, newColumn, newColumn, convert(c.ToNew, "NEW."+column), column, column, convert(c.ToOld, "NEW."+newColumn),
+		),
+		fmt.Sprintf(`CREATE TRIGGER %s AFTER UPDATE OF %s ON %s FOR EACH ROW WHEN NEW.%s IS NOT OLD.%s
+BEGIN
+  UPDATE %s SET %s = %s WHERE rowid = NEW.rowid;
+END`,
+			updateOldName, column, table, column, column,
+			table, newColumn, convert(c.ToNew, "NEW."+column),
+		),
+		fmt.Sprintf(`CREATE TRIGGER %s AFTER UPDATE OF %s ON %s FOR EACH ROW WHEN NEW.%s IS OLD.%s AND NEW.%s IS NOT %s
+BEGIN
+  UPDATE %s SET %s = %s WHERE rowid = NEW.rowid;
+END`,
+			updateNewName, newColumn, table, column, column, newColumn, convert(c.ToNew, "NEW."+column),
+			table, column, convert(c.ToOld, "NEW."+newColumn),
+		),
+	}
+	drop = []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s", insertName),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s", updateOldName),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s", updateNewName),
+	}
+	return create, drop
+}
+
+// convert applies the conversion expr to value, see ColumnChange.ToNew.
+func convert(expr, value string) string {
+	if expr == "" {
+		return value
+	}
+	return strings.ReplaceAll(expr, "%s", value)
+}
+
+func quote(tx *gorm.DB, name string) string {
+	return tx.Statement.Quote(name)
+}
+
+func execAll(tx *gorm.DB, statements []string) error {
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package gormigrate
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// FileStore is a Store keeping the applied migrations in a JSON file, e.g. for
+// embedded or desktop applications whose database should not get a migration
+// table. The file is replaced as a whole on every change.
+type FileStore struct {
+	// Path is the path of the JSON file.
+	Path string
+
+	mu      sync.Mutex
+	running sync.Mutex
+}
+
+// Exists implements Store.
+func (s *FileStore) Exists(*gorm.DB) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := os.Stat(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Ensure implements Store.
+func (s *FileStore) Ensure(*gorm.DB) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := os.Stat(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s.write(nil)
+	}
+	return err
+}
+
+// List implements Store.
+func (s *FileStore) List(*gorm.DB) ([]*AppliedMigration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+// Find implements Store.
+func (s *FileStore) Find(_ *gorm.DB, id string) (*AppliedMigration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	migrations, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return findApplied(migrations, id), nil
+}
+
+// Record implements Store.
+func (s *FileStore) Record(_ *gorm.DB, m *AppliedMigration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	migrations, err := s.read()
+	if err != nil {
+		return err
+	}
+	return s.write(recordApplied(migrations, m))
+}
+
+// Remove implements Store.
+func (s *FileStore) Remove(_ *gorm.DB, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	migrations, err := s.read()
+	if err != nil {
+		return err
+	}
+	return s.write(removeApplied(migrations, id))
+}
+
+// Lock implements Store. It only prevents concurrent runs within the process.
+func (s *FileStore) Lock(*gorm.DB) (func() error, error) {
+	s.running.Lock()
+	return func() error {
+		s.running.Unlock()
+		return nil
+	}, nil
+}
+
+// read returns the migrations of the file, which are none if it does not exist.
+func (s *FileStore) read() ([]*AppliedMigration, error) {
+	content, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var migrations []*AppliedMigration
+	if err := json.Unmarshal(content, &migrations); err != nil {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+// write replaces the file through a rename, so that it is never left half written.
+func (s *FileStore) write(migrations []*AppliedMigration) error {
+	if migrations == nil {
+		migrations = []*AppliedMigration{}
+	}
+	content, err := json.MarshalIndent(migrations, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(content, '\n')); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), s.Path)
+}
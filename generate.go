@@ -0,0 +1,248 @@
+package gormigrate
+
+import (
+	"fmt"
+	"go/format"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// GenerateOptions configures Generate.
+type GenerateOptions struct {
+	// Dir is the directory the migration file is written to.
+	Dir string
+	// Package is the package name of the file. Defaults to the base name of Dir.
+	Package string
+	// Name describes the migration, e.g. "add_user_age". It is part of the file name.
+	Name string
+}
+
+// generatedTable is the part of a generated migration changing one table.
+type generatedTable struct {
+	schema *schema.Schema
+	// fields are the fields of the struct declared for the table in the migration.
+	fields  []*schema.Field
+	create  bool
+	columns []string
+	indexes []string
+}
+
+// Generate writes a new migration file to options.Dir, creating the tables,
+// columns and indexes of the given models that the database misses, as
+// reported by CheckDrift. Rollback drops them. The database is usually
+// migrated to the last migration first.
+//
+// The file declares a func returning the migration, named after its ID, that
+// is to be added to the migration list. The models are copied as structs
+// inside the func, so that the migration does not change when they do.
+// Differences that cannot be generated, like extra columns, are listed as
+// comments to be handled by hand.
+//
+// Generate returns the path of the file, or ErrNoSchemaChanges when the
+// database matches the models. It is meant to be called from a small program
+// run by go generate.
+func Generate(db *gorm.DB, options *GenerateOptions, models ...any) (string, error) {
+	if options.Dir == "" || options.Name == "" {
+		return "", ErrInvalidGenerateOptions
+	}
+	pkg := options.Package
+	if pkg == "" {
+		pkg = filepath.Base(options.Dir)
+	}
+
+	id := time.Now().UTC().Format(timestampIDLayout)
+	src, err := generateMigration(db, id, pkg, models)
+	if err != nil {
+		return "", err
+	}
+
+	filename := filepath.Join(options.Dir, id+"_"+options.Name+".go")
+	if err := writeNewFile(filename, string(src)); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// generateMigration returns the source of the migration with the given ID.
+func generateMigration(db *gorm.DB, id, pkg string, models []any) ([]byte, error) {
+	var (
+		tables  []*generatedTable
+		skipped []*Drift
+	)
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		drifts, err := checkTableDrift(db, model, stmt.Schema)
+		if err != nil {
+			return nil, err
+		}
+
+		table := &generatedTable{schema: stmt.Schema}
+		for _, drift := range drifts {
+			switch drift.Kind {
+			case DriftMissingTable:
+				table.create = true
+				for _, field := range stmt.Schema.Fields {
+					if field.DBName != "" && !field.IgnoreMigration {
+						table.addField(field)
+					}
+				}
+			case DriftMissingColumn:
+				field := stmt.Schema.FieldsByDBName[drift.Name]
+				table.addField(field)
+				table.columns = append(table.columns, field.Name)
+			case DriftMissingIndex:
+				for _, index := range stmt.Schema.ParseIndexes() {
+					if index.Name != drift.Name {
+						continue
+					}
+					for _, option := range index.Fields {
+						table.addField(option.Field)
+					}
+				}
+				table.indexes = append(table.indexes, drift.Name)
+			default:
+				skipped = append(skipped, drift)
+			}
+		}
+		if len(table.fields) > 0 {
+			table.sortFields()
+			tables = append(tables, table)
+		}
+	}
+	if len(tables) == 0 {
+		return nil, ErrNoSchemaChanges
+	}
+
+	imports := map[string]string{
+		"gorm.io/gorm":                           "gorm",
+		"github.com/go-gormigrate/gormigrate/v2": "gormigrate",
+	}
+	var types strings.Builder
+	for _, table := range tables {
+		fmt.Fprintf(&types, "type %s struct {\n", table.schema.Name)
+		for _, field := range table.fields {
+			fmt.Fprintf(&types, "%s %s", field.Name, goType(field.FieldType, imports))
+			if tag := field.Tag.Get("gorm"); tag != "" {
+				fmt.Fprintf(&types, " `gorm:%q`", tag)
+			}
+			types.WriteString("\n")
+		}
+		types.WriteString("}\n\n")
+	}
+
+	var migrate, rollback strings.Builder
+	for _, drift := range skipped {
+		fmt.Fprintf(&migrate, "// TODO: not generated, %s\n", drift)
+	}
+	for _, table := range tables {
+		m := fmt.Sprintf("tx.Table(%q).Migrator()", table.schema.Table)
+		if table.create {
+			writeCall(&migrate, "%s.CreateTable(&%s{})", m, table.schema.Name)
+		}
+		for _, column := range table.columns {
+			writeCall(&migrate, "%s.AddColumn(&%s{}, %q)", m, table.schema.Name, column)
+		}
+		for _, index := range table.indexes {
+			writeCall(&migrate, "%s.CreateIndex(&%s{}, %q)", m, table.schema.Name, index)
+		}
+	}
+	for i := len(tables) - 1; i >= 0; i-- {
+		table := tables[i]
+		m := fmt.Sprintf("tx.Table(%q).Migrator()", table.schema.Table)
+		for j := len(table.indexes) - 1; j >= 0; j-- {
+			writeCall(&rollback, "%s.DropIndex(&%s{}, %q)", m, table.schema.Name, table.indexes[j])
+		}
+		for j := len(table.columns) - 1; j >= 0; j-- {
+			writeCall(&rollback, "%s.DropColumn(&%s{}, %q)", m, table.schema.Name, table.columns[j])
+		}
+		if table.create {
+			writeCall(&rollback, "tx.Migrator().DropTable(%q)", table.schema.Table)
+		}
+	}
+
+	var src strings.Builder
+	fmt.Fprintf(&src, "// Generated by gormigrate.Generate from the differences between the models and the database.\n")
+	fmt.Fprintf(&src, "// Review before committing.\n\npackage %s\n\nimport (\n", pkg)
+	paths := make([]string, 0, len(imports))
+	for importPath := range imports {
+		paths = append(paths, importPath)
+	}
+	// Standard library packages first, like goimports does
+	sort.Slice(paths, func(i, j int) bool {
+		iStd, jStd := !strings.Contains(paths[i], "."), !strings.Contains(paths[j], ".")
+		if iStd != jStd {
+			return iStd
+		}
+		return paths[i] < paths[j]
+	})
+	for i, importPath := range paths {
+		if i > 0 && strings.Contains(importPath, ".") && !strings.Contains(paths[i-1], ".") {
+			src.WriteString("\n")
+		}
+		if name := imports[importPath]; name != path.Base(importPath) {
+			fmt.Fprintf(&src, "%s ", name)
+		}
+		fmt.Fprintf(&src, "%q\n", importPath)
+	}
+	fmt.Fprintf(&src, ")\n\n")
+	fmt.Fprintf(&src, "func migration%s() *gormigrate.Migration {\n%s", id, types.String())
+	fmt.Fprintf(&src, "return &gormigrate.Migration{\nID: %q,\n", id)
+	fmt.Fprintf(&src, "Migrate: func(tx *gorm.DB) error {\n%sreturn nil\n},\n", migrate.String())
+	fmt.Fprintf(&src, "Rollback: func(tx *gorm.DB) error {\n%sreturn nil\n},\n}\n}\n", rollback.String())
+	return format.Source([]byte(src.String()))
+}
+
+func (t *generatedTable) addField(field *schema.Field) {
+	for _, f := range t.fields {
+		if f == field {
+			return
+		}
+	}
+	t.fields = append(t.fields, field)
+}
+
+// sortFields sorts the fields in the order of the model.
+func (t *generatedTable) sortFields() {
+	positions := make(map[*schema.Field]int, len(t.schema.Fields))
+	for i, field := range t.schema.Fields {
+		positions[field] = i
+	}
+	sort.SliceStable(t.fields, func(i, j int) bool {
+		return positions[t.fields[i]] < positions[t.fields[j]]
+	})
+}
+
+func writeCall(w *strings.Builder, call string, args ...any) {
+	fmt.Fprintf(w, "if err := "+call+"; err != nil {\nreturn err\n}\n", args...)
+}
+
+// goType returns the Go source of t, adding the packages it refers to to imports.
+func goType(t reflect.Type, imports map[string]string) string {
+	if t.Name() != "" && t.PkgPath() != "" {
+		name := t.String()
+		imports[t.PkgPath()] = name[:strings.Index(name, ".")]
+		return name
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + goType(t.Elem(), imports)
+	case reflect.Slice:
+		return "[]" + goType(t.Elem(), imports)
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), goType(t.Elem(), imports))
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", goType(t.Key(), imports), goType(t.Elem(), imports))
+	default:
+		return t.String()
+	}
+}
@@ -2,7 +2,11 @@ module github.com/go-gormigrate/gormigrate/v2
 
 go 1.18
 
-require gorm.io/gorm v1.26.1
+require (
+	go.opentelemetry.io/otel v1.14.0
+	go.opentelemetry.io/otel/trace v1.14.0
+	gorm.io/gorm v1.26.1
+)
 
 require (
 	github.com/jinzhu/inflection v1.0.0 // indirect
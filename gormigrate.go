@@ -5,8 +5,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"reflect"
+	"sort"
+	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
@@ -23,13 +27,44 @@ type RollbackFunc func(*gorm.DB) error
 // InitSchemaFunc is the func signature for initializing the schema.
 type InitSchemaFunc func(*gorm.DB) error
 
+// VerifyFunc is the func signature for verifying a migration after it ran.
+type VerifyFunc func(*gorm.DB) error
+
+// PreconditionFunc is the func signature for checking whether a migration should run.
+type PreconditionFunc func(*gorm.DB) (bool, error)
+
+// PreconditionAction is what happens when the precondition of a migration fails.
+type PreconditionAction int
+
+const (
+	// PreconditionHalt stops the run with a PreconditionFailedError.
+	PreconditionHalt PreconditionAction = iota
+	// PreconditionSkipAndMarkRan skips the migration and records it as ran,
+	// reported as StateSkippedByPrecondition by Status.
+	PreconditionSkipAndMarkRan
+	// PreconditionSkip skips the migration and leaves it pending.
+	PreconditionSkip
+)
+
 // Options define options for all migrations.
 type Options struct {
-	// TableName is the migration table.
+	// TableName is the migration table. It can be qualified by a schema, like
+	// "ops.migrations".
 	TableName string
+	// TableSchema is the schema of the migration table, overriding the one of
+	// TableName. It is quoted for the dialect.
+	TableSchema string
+	// CreateTableSchema creates TableSchema along with the migration table,
+	// on Postgres, MySQL and SQL Server.
+	CreateTableSchema bool
+	// UseTablePrefix prepends the TablePrefix of the gorm NamingStrategy to
+	// TableName.
+	UseTablePrefix bool
 	// IDColumnName is the name of column where the migration id will be stored.
+	// The column of an existing migration table is renamed when it changes.
 	IDColumnName string
-	// IDColumnSize is the length of the migration id column
+	// IDColumnSize is the length of the migration id column. The column of an
+	// existing migration table is resized when it changes.
 	IDColumnSize int
 	// UseTransaction makes Gormigrate execute migrations inside a single transaction.
 	// Keep in mind that not all databases support DDL commands inside transactions.
@@ -37,6 +72,51 @@ type Options struct {
 	// ValidateUnknownMigrations will cause migrate to fail if there's unknown migration
 	// IDs in the database
 	ValidateUnknownMigrations bool
+	// CaptureStatements records the SQL statements executed by each migration,
+	// which are then available in the run report. The capturing callbacks are
+	// registered on the database by New.
+	CaptureStatements bool
+	// StatementsTableName is the table where captured statements are stored,
+	// keyed by migration ID. Statements are not stored when empty.
+	StatementsTableName string
+	// IncludeTags restricts the tagged migrations that run to the ones having at
+	// least one of these tags. Untagged migrations always run.
+	IncludeTags []string
+	// ExcludeTags prevents the migrations having any of these tags from running.
+	ExcludeTags []string
+	// CheckpointTableName is the table where the progress of backfill migrations is stored.
+	CheckpointTableName string
+	// IDFormat makes migrating fail when a migration ID is not in this format.
+	// IDs are free-form when empty.
+	IDFormat IDFormat
+	// TracerProvider creates the tracer of the OpenTelemetry spans of each run
+	// and of each step of its migrations. Nothing is traced when nil.
+	TracerProvider trace.TracerProvider
+	// Metrics records the outcome of migrations and the state of the
+	// database after each run and each call to Status. Nothing is recorded when nil.
+	Metrics Metrics
+	// Retry retries Migrate, MigrateTo and MigratePhase when they fail with a
+	// transient error. Requires UseTransaction. Nothing is retried when nil.
+	Retry *RetryPolicy
+	// LockTimeout limits how long the statements of the Migrate and Rollback
+	// functions wait for a lock, e.g. behind a long query of the application.
+	// It is lock_timeout on Postgres, innodb_lock_wait_timeout on MySQL, in
+	// whole seconds, LOCK_TIMEOUT on SQL Server and busy_timeout on SQLite.
+	// Nothing is limited when zero.
+	LockTimeout time.Duration
+	// StatementTimeout limits how long the statements of the Migrate and
+	// Rollback functions run. It is statement_timeout on Postgres,
+	// max_execution_time on MySQL, which only limits SELECT statements, and
+	// max_statement_time on MariaDB. Other dialects return
+	// ErrTimeoutUnsupported. Nothing is limited when zero.
+	StatementTimeout time.Duration
+	// AdvisoryLock makes concurrent runs wait for each other, through an
+	// advisory lock on Postgres, MySQL and SQL Server held on a connection of
+	// its own, see TableStore.AdvisoryLock.
+	AdvisoryLock bool
+	// Store keeps track of the migrations that ran. Defaults to a TableStore
+	// configured by TableName, IDColumnName, IDColumnSize and AdvisoryLock.
+	Store Store
 }
 
 // Migration represents a database migration (a modification to be made on the database).
@@ -47,15 +127,56 @@ type Migration struct {
 	Migrate MigrateFunc
 	// Rollback will be executed on rollback. Can be nil.
 	Rollback RollbackFunc
+	// DialectMigrate overrides Migrate for the dialects it has a key for,
+	// as returned by Dialector.Name(), e.g. "postgres", "mysql" or "sqlite".
+	DialectMigrate map[string]MigrateFunc
+	// DialectRollback overrides Rollback for the dialects it has a key for.
+	DialectRollback map[string]RollbackFunc
+	// Verify will be executed right after Migrate, to check the outcome of the
+	// migration. An error fails the migration. Can be nil.
+	Verify VerifyFunc
+	// Repeatable makes the migration run after all other migrations, every time
+	// its Checksum differs from the one recorded when it last ran.
+	// Useful for views, stored functions and triggers.
+	Repeatable bool
+	// Checksum identifies the content of a repeatable migration.
+	// See the Checksum function.
+	Checksum string
+	// Tags are used to select the migrations to run with Options.IncludeTags
+	// and Options.ExcludeTags, e.g. "seed" or "dev-only".
+	Tags []string
+	// Precondition is checked before running the migration. Can be nil.
+	Precondition PreconditionFunc
+	// OnPreconditionFail is what happens when Precondition returns false.
+	OnPreconditionFail PreconditionAction
+	// Backfill makes the migration process rows in resumable batches,
+	// instead of running Migrate. Can be nil.
+	Backfill *Backfill
+	// Phase is the deploy phase the migration belongs to. Defaults to PhasePreDeploy.
+	Phase Phase
+	// Retry overrides Options.Retry when the migration fails. Can be nil.
+	Retry *RetryPolicy
+	// LockTimeout overrides Options.LockTimeout when not zero. A negative
+	// value disables it.
+	LockTimeout time.Duration
+	// StatementTimeout overrides Options.StatementTimeout when not zero. A
+	// negative value disables it.
+	StatementTimeout time.Duration
 }
 
 // Gormigrate represents a collection of all migrations of a database schema.
 type Gormigrate struct {
 	db         *gorm.DB
 	tx         *gorm.DB
+	stateDB    *gorm.DB
+	state      *gorm.DB
 	options    *Options
 	migrations []*Migration
 	initSchema InitSchemaFunc
+	report     *Report
+	// captureErr is the error of registering the callbacks capturing
+	// statements, returned by the first migration run.
+	captureErr error
 }
 
 // ReservedIDError is returned when a migration is using a reserved ID
@@ -76,14 +197,130 @@ func (e *DuplicatedIDError) Error() string {
 	return fmt.Sprintf(`gormigrate: Duplicated migration ID: "%s"`, e.ID)
 }
 
+// IDTooLongError is returned when a migration ID is longer than Options.IDColumnSize
+type IDTooLongError struct {
+	ID   string
+	Size int
+}
+
+func (e *IDTooLongError) Error() string {
+	return fmt.Sprintf(`gormigrate: Migration ID longer than %d characters: "%s"`, e.Size, e.ID)
+}
+
+// InvalidIDError is returned when a migration ID is not in Options.IDFormat
+type InvalidIDError struct {
+	ID     string
+	Format IDFormat
+}
+
+func (e *InvalidIDError) Error() string {
+	return fmt.Sprintf(`gormigrate: Migration ID not in %s format: "%s"`, e.Format, e.ID)
+}
+
+// PreconditionFailedError is returned when the precondition of a migration
+// fails and its OnPreconditionFail is PreconditionHalt
+type PreconditionFailedError struct {
+	ID string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return fmt.Sprintf(`gormigrate: Precondition failed for migration ID: "%s"`, e.ID)
+}
+
+// VerificationFailedError is returned when the Verify function of a migration fails
+type VerificationFailedError struct {
+	ID  string
+	Err error
+}
+
+func (e *VerificationFailedError) Error() string {
+	return fmt.Sprintf(`gormigrate: Verification failed for migration ID: "%s": %v`, e.ID, e.Err)
+}
+
+func (e *VerificationFailedError) Unwrap() error {
+	return e.Err
+}
+
+// PhaseOrderError is returned when a post-deploy migration would run before
+// an earlier pre-deploy migration
+type PhaseOrderError struct {
+	ID        string
+	PendingID string
+}
+
+func (e *PhaseOrderError) Error() string {
+	return fmt.Sprintf(`gormigrate: Post-deploy migration ID "%s" cannot run before pre-deploy migration ID "%s"`, e.ID, e.PendingID)
+}
+
+// UnsupportedDialectError is returned when a migration has neither a function
+// for the current dialect nor a fallback function
+type UnsupportedDialectError struct {
+	ID      string
+	Step    Step
+	Dialect string
+}
+
+func (e *UnsupportedDialectError) Error() string {
+	return fmt.Sprintf(`gormigrate: No %s function for dialect "%s" in migration ID: "%s"`, e.Step, e.Dialect, e.ID)
+}
+
+// HistoryConflictError is returned when an imported history conflicts with
+// the migrations recorded in the store
+type HistoryConflictError struct {
+	Conflicts []*HistoryConflict
+}
+
+func (e *HistoryConflictError) Error() string {
+	ids := make([]string, len(e.Conflicts))
+	for i, conflict := range e.Conflicts {
+		ids[i] = conflict.ID
+	}
+	return fmt.Sprintf(`gormigrate: Imported history conflicts for migration IDs: "%s"`, strings.Join(ids, `", "`))
+}
+
+// PendingMigrationsError is returned by Check when migrations did not run yet
+type PendingMigrationsError struct {
+	IDs []string
+}
+
+func (e *PendingMigrationsError) Error() string {
+	return fmt.Sprintf(`gormigrate: Pending migrations: "%s"`, strings.Join(e.IDs, `", "`))
+}
+
+// UnknownMigrationsError is returned by Check when the database has migrations
+// that do not exist in the code, e.g. ran by a newer version of the application
+type UnknownMigrationsError struct {
+	IDs []string
+}
+
+func (e *UnknownMigrationsError) Error() string {
+	return fmt.Sprintf(`gormigrate: Found migrations in DB that do not exist in code: "%s"`, strings.Join(e.IDs, `", "`))
+}
+
 var (
 	// DefaultOptions can be used if you don't want to think about options.
 	DefaultOptions = &Options{
 		TableName:                 "migrations",
+		TableSchema:               "",
+		CreateTableSchema:         false,
+		UseTablePrefix:            false,
 		IDColumnName:              "id",
 		IDColumnSize:              255,
 		UseTransaction:            false,
 		ValidateUnknownMigrations: false,
+		CaptureStatements:         false,
+		StatementsTableName:       "",
+		IncludeTags:               nil,
+		ExcludeTags:               nil,
+		CheckpointTableName:       "migration_checkpoints",
+		IDFormat:                  "",
+		TracerProvider:            nil,
+		Metrics:                   nil,
+		Retry:                     nil,
+		LockTimeout:               0,
+		StatementTimeout:          0,
+		AdvisoryLock:              false,
+		Store:                     nil,
 	}
 
 	// ErrRollbackImpossible is returned when trying to rollback a migration
@@ -106,6 +343,70 @@ var (
 
 	// ErrUnknownPastMigration is returned if a migration exists in the DB that doesn't exist in the code
 	ErrUnknownPastMigration = errors.New("gormigrate: Found migration in DB that does not exist in code")
+
+	// ErrMissingChecksum is returned when a repeatable migration has no checksum
+	ErrMissingChecksum = errors.New("gormigrate: Missing checksum in repeatable migration")
+
+	// ErrMissingPhase is returned when migrating a phase without naming it
+	ErrMissingPhase = errors.New("gormigrate: Missing phase")
+
+	// ErrInvalidBackfill is returned when a backfill has no table or no batch function
+	ErrInvalidBackfill = errors.New("gormigrate: Missing table or batch function in backfill")
+
+	// ErrInvalidColumnChange is returned when a column change misses its table,
+	// one of its columns or the type of the existing column
+	ErrInvalidColumnChange = errors.New("gormigrate: Missing table, column, new column or type in column change")
+
+	// ErrScriptBackfill is returned when generating a script for a pending backfill migration
+	ErrScriptBackfill = errors.New("gormigrate: Backfill migrations cannot be scripted")
+
+	// ErrInvalidGenerateOptions is returned when generating a migration without
+	// a directory or a name
+	ErrInvalidGenerateOptions = errors.New("gormigrate: Missing directory or name to generate a migration")
+
+	// ErrNoSchemaChanges is returned when generating a migration for models
+	// that match the database
+	ErrNoSchemaChanges = errors.New("gormigrate: No schema changes to generate")
+
+	// ErrUnknownIDFormat is returned when validating IDs against an unknown format
+	ErrUnknownIDFormat = errors.New("gormigrate: Unknown ID format")
+
+	// ErrInvalidScaffoldOptions is returned when scaffolding a migration without
+	// a directory or a name
+	ErrInvalidScaffoldOptions = errors.New("gormigrate: Missing directory or name to scaffold a migration")
+
+	// ErrScriptStateDB is returned when generating a script with a separate
+	// state database, see UseStateDB
+	ErrScriptStateDB = errors.New("gormigrate: Scripts cannot be generated with a separate state database")
+
+	// ErrScriptStore is returned when generating a script with a Store that
+	// is not a TableStore
+	ErrScriptStore = errors.New("gormigrate: Scripts can only be generated with a TableStore")
+
+	// ErrCreateSchemaUnsupported is returned when creating the schema of the
+	// migration table with a dialect that cannot create schemas
+	ErrCreateSchemaUnsupported = errors.New("gormigrate: Creating schemas is not supported by the dialect")
+
+	// ErrNoStatementsTable is returned when exporting statements without
+	// Options.StatementsTableName set
+	ErrNoStatementsTable = errors.New("gormigrate: No statements table defined")
+
+	// ErrMissingMigrationTable is returned by Check when the migration table
+	// does not exist
+	ErrMissingMigrationTable = errors.New("gormigrate: Migration table does not exist")
+
+	// ErrTimeoutUnsupported is returned when running a migration with a lock
+	// or statement timeout the dialect cannot set
+	ErrTimeoutUnsupported = errors.New("gormigrate: Timeout is not supported by the dialect")
+
+	// ErrRetryWithoutTransaction is returned when retrying migrations without
+	// UseTransaction, which would run a failed migration over its own changes
+	ErrRetryWithoutTransaction = errors.New("gormigrate: Retrying migrations requires UseTransaction")
+
+	// ErrLockSingleConnection is returned when taking the advisory lock of a
+	// TableStore on a pool limited to a single connection, which the run
+	// would then wait for forever
+	ErrLockSingleConnection = errors.New("gormigrate: Advisory lock requires a pool of at least two connections")
 )
 
 // New returns a new Gormigrate.
@@ -122,11 +423,20 @@ func New(db *gorm.DB, options *Options, migrations []*Migration) *Gormigrate {
 	if options.IDColumnSize == 0 {
 		options.IDColumnSize = DefaultOptions.IDColumnSize
 	}
-	return &Gormigrate{
+	if options.CheckpointTableName == "" {
+		options.CheckpointTableName = DefaultOptions.CheckpointTableName
+	}
+	g := &Gormigrate{
 		db:         db,
 		options:    options,
 		migrations: migrations,
 	}
+	if options.CaptureStatements {
+		// Registering callbacks while db runs queries is a data race, so it
+		// is done before db is used by migrations
+		g.captureErr = registerCaptureCallbacks(db)
+	}
+	return g
 }
 
 // InitSchema sets a function that is run if no migration is found.
@@ -137,27 +447,53 @@ func (g *Gormigrate) InitSchema(initSchema InitSchemaFunc) {
 	g.initSchema = initSchema
 }
 
+// UseStateDB keeps the migration table in db instead of the migrated database,
+// e.g. a central database tracking the migrations of many databases, or when
+// the migrated database must not get extra tables. The tables of captured
+// statements and backfill checkpoints are kept in db as well.
+//
+// With Options.UseTransaction, both databases get their own transaction, the
+// migrated database being committed first: the two commits are not atomic.
+func (g *Gormigrate) UseStateDB(db *gorm.DB) {
+	g.stateDB = db
+}
+
 // Migrate executes all migrations that did not run yet.
 func (g *Gormigrate) Migrate() error {
 	if !g.hasMigrations() {
 		return ErrNoMigrationDefined
 	}
-	var targetMigrationID string
-	if len(g.migrations) > 0 {
-		targetMigrationID = g.migrations[len(g.migrations)-1].ID
-	}
-	return g.migrate(targetMigrationID)
+	return g.migrate(g.lastVersionedID(), "")
 }
 
 // MigrateTo executes all migrations that did not run yet up to the migration that matches `migrationID`.
+// Repeatable migrations only run when `migrationID` is the last migration.
 func (g *Gormigrate) MigrateTo(migrationID string) error {
 	if err := g.checkIDExist(migrationID); err != nil {
 		return err
 	}
-	return g.migrate(migrationID)
+	return g.migrate(migrationID, "")
 }
 
-func (g *Gormigrate) migrate(migrationID string) error {
+// migrate runs the migrations up to `migrationID`. When phase is not empty,
+// only the migrations of this phase run.
+func (g *Gormigrate) migrate(migrationID string, phase Phase) (err error) {
+	attrs := []attribute.KeyValue{MigrationIDKey.String(migrationID)}
+	if phase != "" {
+		attrs = append(attrs, PhaseKey.String(string(phase)))
+	}
+	defer g.startRun("gormigrate.Migrate", attrs...)(&err)
+
+	if err := g.checkRetry(); err != nil {
+		return err
+	}
+	return g.retry(func() error {
+		return g.migrateOnce(migrationID, phase)
+	})
+}
+
+// migrateOnce makes a single attempt at running the migrations up to `migrationID`.
+func (g *Gormigrate) migrateOnce(migrationID string, phase Phase) error {
 	if !g.hasMigrations() {
 		return ErrNoMigrationDefined
 	}
@@ -170,6 +506,16 @@ func (g *Gormigrate) migrate(migrationID string) error {
 		return err
 	}
 
+	if err := g.checkIDs(); err != nil {
+		return err
+	}
+
+	unlock, err := g.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	g.begin()
 	defer g.rollback()
 
@@ -201,13 +547,23 @@ func (g *Gormigrate) migrate(migrationID string) error {
 	}
 
 	for _, migration := range g.migrations {
-		if err := g.runMigration(migration); err != nil {
-			return err
+		// The target is checked even when it does not run itself, so that a
+		// filtered target does not let the migrations after it run
+		skipped := migration.Repeatable || !g.selected(migration) || (phase != "" && migration.phase() != phase)
+		if !skipped {
+			if err := g.runMigration(migration); err != nil {
+				return err
+			}
 		}
 		if migrationID != "" && migration.ID == migrationID {
 			break
 		}
 	}
+	if migrationID == g.lastVersionedID() {
+		if err := g.runRepeatableMigrations(phase); err != nil {
+			return err
+		}
+	}
 	return g.commit()
 }
 
@@ -241,7 +597,7 @@ func (g *Gormigrate) checkDuplicatedID() error {
 
 func (g *Gormigrate) checkIDExist(migrationID string) error {
 	for _, migrate := range g.migrations {
-		if migrate.ID == migrationID {
+		if migrate.ID == migrationID && !migrate.Repeatable {
 			return nil
 		}
 	}
@@ -249,11 +605,19 @@ func (g *Gormigrate) checkIDExist(migrationID string) error {
 }
 
 // RollbackLast undo the last migration
-func (g *Gormigrate) RollbackLast() error {
+func (g *Gormigrate) RollbackLast() (err error) {
+	defer g.startRun("gormigrate.RollbackLast")(&err)
+
 	if len(g.migrations) == 0 {
 		return ErrNoMigrationDefined
 	}
 
+	unlock, err := g.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	g.begin()
 	defer g.rollback()
 
@@ -270,7 +634,10 @@ func (g *Gormigrate) RollbackLast() error {
 
 // RollbackTo undoes migrations up to the given migration that matches the `migrationID`.
 // Migration with the matching `migrationID` is not rolled back.
-func (g *Gormigrate) RollbackTo(migrationID string) error {
+// Repeatable migrations are never rolled back by RollbackTo or RollbackLast.
+func (g *Gormigrate) RollbackTo(migrationID string) (err error) {
+	defer g.startRun("gormigrate.RollbackTo", MigrationIDKey.String(migrationID))(&err)
+
 	if len(g.migrations) == 0 {
 		return ErrNoMigrationDefined
 	}
@@ -279,6 +646,12 @@ func (g *Gormigrate) RollbackTo(migrationID string) error {
 		return err
 	}
 
+	unlock, err := g.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	g.begin()
 	defer g.rollback()
 
@@ -287,6 +660,9 @@ func (g *Gormigrate) RollbackTo(migrationID string) error {
 		if migration.ID == migrationID {
 			break
 		}
+		if migration.Repeatable || !g.selected(migration) {
+			continue
+		}
 		migrationRan, err := g.migrationRan(migration)
 		if err != nil {
 			return err
@@ -303,6 +679,9 @@ func (g *Gormigrate) RollbackTo(migrationID string) error {
 func (g *Gormigrate) getLastRunMigration() (*Migration, error) {
 	for i := len(g.migrations) - 1; i >= 0; i-- {
 		migration := g.migrations[i]
+		if migration.Repeatable || !g.selected(migration) {
+			continue
+		}
 
 		migrationRan, err := g.migrationRan(migration)
 		if err != nil {
@@ -317,7 +696,15 @@ func (g *Gormigrate) getLastRunMigration() (*Migration, error) {
 }
 
 // RollbackMigration undo a migration.
-func (g *Gormigrate) RollbackMigration(m *Migration) error {
+func (g *Gormigrate) RollbackMigration(m *Migration) (err error) {
+	defer g.startRun("gormigrate.RollbackMigration", MigrationIDKey.String(m.ID))(&err)
+
+	unlock, err := g.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	g.begin()
 	defer g.rollback()
 
@@ -328,28 +715,36 @@ func (g *Gormigrate) RollbackMigration(m *Migration) error {
 }
 
 func (g *Gormigrate) rollbackMigration(m *Migration) error {
-	if m.Rollback == nil {
-		return ErrRollbackImpossible
+	rollback, err := m.rollbackFunc(g.tx.Dialector.Name())
+	if err != nil {
+		return err
 	}
 
-	if err := m.Rollback(g.tx); err != nil {
+	if err := g.run(m.ID, StepRollback, g.withTimeouts(m, rollback)); err != nil {
 		return err
 	}
-
-	cond := fmt.Sprintf("%s = ?", g.options.IDColumnName)
-	return g.tx.Table(g.options.TableName).Where(cond, m.ID).Delete(g.model()).Error
+	return g.deleteMigration(m.ID)
 }
 
 func (g *Gormigrate) runInitSchema() error {
-	if err := g.initSchema(g.tx); err != nil {
+	if err := g.run(initSchemaMigrationID, StepInitSchema, g.initSchema); err != nil {
 		return err
 	}
-	if err := g.insertMigration(initSchemaMigrationID); err != nil {
+	return g.insertInitSchemaMigrations()
+}
+
+// insertInitSchemaMigrations marks the schema as initialised and
+// all migrations as applied.
+func (g *Gormigrate) insertInitSchemaMigrations() error {
+	if err := g.insertMigration(&AppliedMigration{ID: initSchemaMigrationID}); err != nil {
 		return err
 	}
 
 	for _, migration := range g.migrations {
-		if err := g.insertMigration(migration.ID); err != nil {
+		if !g.selected(migration) {
+			continue
+		}
+		if err := g.insertMigration(&AppliedMigration{ID: migration.ID, Checksum: migration.Checksum}); err != nil {
 			return err
 		}
 	}
@@ -367,52 +762,33 @@ func (g *Gormigrate) runMigration(migration *Migration) error {
 		return err
 	}
 	if !migrationRan {
-		if err := migration.Migrate(g.tx); err != nil {
+		if err := g.checkPhaseOrder(migration); err != nil {
+			return err
+		}
+		shouldRun, err := g.checkPrecondition(migration, func(skipped bool) error {
+			return g.insertMigration(&AppliedMigration{ID: migration.ID, Skipped: skipped})
+		})
+		if err != nil || !shouldRun {
+			return err
+		}
+		if err := g.runMigrate(migration); err != nil {
 			return err
 		}
 
-		if err := g.insertMigration(migration.ID); err != nil {
+		if err := g.insertMigration(&AppliedMigration{ID: migration.ID}); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// model returns pointer to dynamically created gorm migration model struct value
-//
-//	struct defined as {
-//	  ID string `gorm:"primaryKey;column:<Options.IDColumnName>;size:<Options.IDColumnSize>"`
-//	}
-func (g *Gormigrate) model() any {
-	f := reflect.StructField{
-		Name: reflect.ValueOf("ID").Interface().(string),
-		Type: reflect.TypeOf(""),
-		Tag: reflect.StructTag(fmt.Sprintf(
-			`gorm:"primaryKey;column:%s;size:%d"`,
-			g.options.IDColumnName,
-			g.options.IDColumnSize,
-		)),
-	}
-	structType := reflect.StructOf([]reflect.StructField{f})
-	structValue := reflect.New(structType).Elem()
-	return structValue.Addr().Interface()
-}
-
 func (g *Gormigrate) createMigrationTableIfNotExists() error {
-	if g.tx.Migrator().HasTable(g.options.TableName) {
-		return nil
-	}
-	return g.tx.Table(g.options.TableName).AutoMigrate(g.model())
+	return g.stateStore().Ensure(g.state)
 }
 
 func (g *Gormigrate) migrationRan(m *Migration) (bool, error) {
-	var count int64
-	err := g.tx.
-		Table(g.options.TableName).
-		Where(fmt.Sprintf("%s = ?", g.options.IDColumnName), m.ID).
-		Count(&count).
-		Error
-	return count > 0, err
+	applied, err := g.stateStore().Find(g.state, m.ID)
+	return applied != nil, err
 }
 
 // The schema can be initialised only if it hasn't been initialised yet
@@ -427,24 +803,22 @@ func (g *Gormigrate) canInitializeSchema() (bool, error) {
 	}
 
 	// If the ID doesn't exist, we also want the list of migrations to be empty
-	var count int64
-	err = g.tx.
-		Table(g.options.TableName).
-		Count(&count).
-		Error
-	return count == 0, err
+	applied, err := g.stateStore().List(g.state)
+	return len(applied) == 0, err
 }
 
 func (g *Gormigrate) unknownMigrationsHaveHappened() (bool, error) {
-	rows, err := g.tx.Table(g.options.TableName).Select(g.options.IDColumnName).Rows()
+	unknownIDs, err := g.unknownMigrationIDs()
+	return len(unknownIDs) > 0, err
+}
+
+// unknownMigrationIDs returns the IDs of the applied migrations that do not
+// exist in the code.
+func (g *Gormigrate) unknownMigrationIDs() ([]string, error) {
+	applied, err := g.stateStore().List(g.state)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			g.tx.Logger.Error(context.TODO(), err.Error())
-		}
-	}()
 
 	validIDSet := make(map[string]struct{}, len(g.migrations)+1)
 	validIDSet[initSchemaMigrationID] = struct{}{}
@@ -452,36 +826,72 @@ func (g *Gormigrate) unknownMigrationsHaveHappened() (bool, error) {
 		validIDSet[migration.ID] = struct{}{}
 	}
 
-	for rows.Next() {
-		var pastMigrationID string
-		if err := rows.Scan(&pastMigrationID); err != nil {
-			return false, err
-		}
-		if _, ok := validIDSet[pastMigrationID]; !ok {
-			return true, nil
+	var unknownIDs []string
+	for _, pastMigration := range applied {
+		if _, ok := validIDSet[pastMigration.ID]; !ok {
+			unknownIDs = append(unknownIDs, pastMigration.ID)
 		}
 	}
+	sort.Strings(unknownIDs)
+	return unknownIDs, nil
+}
 
-	return false, nil
+func (g *Gormigrate) insertMigration(m *AppliedMigration) error {
+	return g.stateStore().Record(g.state, m)
 }
 
-func (g *Gormigrate) insertMigration(id string) error {
-	record := g.model()
-	reflect.ValueOf(record).Elem().FieldByName("ID").SetString(id)
-	return g.tx.Table(g.options.TableName).Create(record).Error
+func (g *Gormigrate) deleteMigration(id string) error {
+	return g.stateStore().Remove(g.state, id)
+}
+
+// lock locks the store against concurrent runs, returning the function unlocking it.
+func (g *Gormigrate) lock() (func(), error) {
+	db := g.stateDatabase()
+	unlock, err := g.stateStore().Lock(db)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := unlock(); err != nil {
+			db.Logger.Error(context.TODO(), err.Error())
+		}
+	}, nil
 }
 
 func (g *Gormigrate) begin() {
+	g.report = &Report{}
+	g.beginTransaction()
+}
+
+// beginTransaction starts the transactions of the run, when
+// Options.UseTransaction is set.
+func (g *Gormigrate) beginTransaction() {
 	if g.options.UseTransaction {
 		g.tx = g.db.Begin()
 	} else {
 		g.tx = g.db
 	}
+	g.state = g.tx
+	if g.stateDB != nil {
+		if g.options.UseTransaction {
+			g.state = g.stateDB.Begin()
+		} else {
+			g.state = g.stateDB
+		}
+	}
 }
 
 func (g *Gormigrate) commit() error {
 	if g.options.UseTransaction {
-		return g.tx.Commit().Error
+		// The migrated database is committed first, so that a failure of the
+		// second commit leaves applied migrations unrecorded instead of
+		// recording migrations that were not applied.
+		if err := g.tx.Commit().Error; err != nil {
+			return err
+		}
+		if g.stateDB != nil {
+			return g.state.Commit().Error
+		}
 	}
 	return nil
 }
@@ -489,5 +899,16 @@ func (g *Gormigrate) commit() error {
 func (g *Gormigrate) rollback() {
 	if g.options.UseTransaction {
 		g.tx.Rollback()
+		if g.stateDB != nil {
+			g.state.Rollback()
+		}
+	}
+}
+
+// stateDatabase returns the database where the migration table is kept.
+func (g *Gormigrate) stateDatabase() *gorm.DB {
+	if g.stateDB != nil {
+		return g.stateDB
 	}
+	return g.db
 }
@@ -0,0 +1,30 @@
+module github.com/go-gormigrate/gormigrate/v2/gormigrateprom
+
+go 1.22
+
+// gormigrate.Metrics is introduced by v2.2.0, which must be tagged before
+// this module.
+require (
+	github.com/go-gormigrate/gormigrate/v2 v2.2.0
+	github.com/prometheus/client_golang v1.20.5
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.55.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
+	go.opentelemetry.io/otel v1.14.0 // indirect
+	go.opentelemetry.io/otel/trace v1.14.0 // indirect
+	golang.org/x/sys v0.22.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+	gorm.io/gorm v1.26.1 // indirect
+)
+
+// Builds against the parent module during development.
+replace github.com/go-gormigrate/gormigrate/v2 => ../
@@ -0,0 +1,58 @@
+package gormigratetest
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+// InitSchemaError is returned by CompareInitSchema when the schema created by
+// InitSchema differs from the one created by the migrations.
+type InitSchemaError struct {
+	// Diff holds the differences between the schema created by the migrations
+	// and the one created by InitSchema, see Diff.
+	Diff []string
+}
+
+func (e *InitSchemaError) Error() string {
+	return fmt.Sprintf("gormigratetest: InitSchema does not match the migrations:\n%s", strings.Join(e.Diff, "\n"))
+}
+
+// CompareInitSchema checks that initSchema creates the same schema as running
+// every migration from scratch. It migrates initDB with initSchema, and chainDB
+// with the migrations only, then compares the tables, columns, indexes and
+// foreign keys of both databases. initDB and chainDB must be two fresh
+// databases of the same dialect, and options can be nil.
+//
+// It returns an *InitSchemaError listing the differences, where lines
+// starting with "-" are missing from the schema created by initSchema and
+// lines starting with "+" are only in it.
+func CompareInitSchema(initDB, chainDB *gorm.DB, options *gormigrate.Options, migrations []*gormigrate.Migration, initSchema gormigrate.InitSchemaFunc) error {
+	initOptions := copyOptions(options)
+	m := gormigrate.New(initDB, initOptions, migrations)
+	m.InitSchema(initSchema)
+	if err := m.Migrate(); err != nil {
+		return fmt.Errorf("gormigratetest: migrating with InitSchema: %w", err)
+	}
+	initSnapshot, err := Snapshot(initDB, bookkeepingTables(initOptions)...)
+	if err != nil {
+		return err
+	}
+
+	chainOptions := copyOptions(options)
+	if err := gormigrate.New(chainDB, chainOptions, migrations).Migrate(); err != nil {
+		return fmt.Errorf("gormigratetest: migrating without InitSchema: %w", err)
+	}
+	chainSnapshot, err := Snapshot(chainDB, bookkeepingTables(chainOptions)...)
+	if err != nil {
+		return err
+	}
+
+	if diff := Diff(chainSnapshot, initSnapshot); len(diff) > 0 {
+		return &InitSchemaError{Diff: diff}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Package gormigratetest provides helpers to test migrations against a
+// scratch database, e.g. an in-memory sqlite one.
+package gormigratetest
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+// RollbackError is returned by RoundTrip for a migration whose Rollback is
+// missing, fails, or does not restore the schema.
+type RollbackError struct {
+	// ID is the migration identifier.
+	ID string
+	// Err is the error returned when rolling back the migration, if any.
+	Err error
+	// Diff holds the differences between the schema before the migration ran
+	// and after it was rolled back, see Diff.
+	Diff []string
+}
+
+func (e *RollbackError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf(`gormigratetest: Rollback of migration "%s" failed: %s`, e.ID, e.Err)
+	}
+	return fmt.Sprintf("gormigratetest: Rollback of migration \"%s\" does not restore the schema:\n%s", e.ID, strings.Join(e.Diff, "\n"))
+}
+
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// RoundTrip checks the Rollback of every migration. It applies each migration,
+// rolls it back, checks that the schema is the same as before the migration,
+// applies it again and continues with the next one. Migrations that do not
+// run, left out by tags or skipped by their precondition, are not checked.
+// db must be a fresh database, and options can be nil.
+//
+// It returns a *RollbackError for the first migration whose Rollback is
+// missing or is not the inverse of Migrate.
+//
+//	func TestRollbacks(t *testing.T) {
+//		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		if err := gormigratetest.RoundTrip(db, nil, migrations); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+func RoundTrip(db *gorm.DB, options *gormigrate.Options, migrations []*gormigrate.Migration) error {
+	options = copyOptions(options)
+	m := gormigrate.New(db, options, migrations)
+	exclude := bookkeepingTables(options)
+
+	for _, migration := range migrations {
+		if migration.Repeatable {
+			continue
+		}
+		before, err := Snapshot(db, exclude...)
+		if err != nil {
+			return err
+		}
+		if err := m.MigrateTo(migration.ID); err != nil {
+			return fmt.Errorf("gormigratetest: migrating to %q: %w", migration.ID, err)
+		}
+		applied, err := isApplied(m, migration.ID)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			// Left out by tags or skipped by its precondition
+			continue
+		}
+		if err := m.RollbackMigration(migration); err != nil {
+			return &RollbackError{ID: migration.ID, Err: err}
+		}
+		after, err := Snapshot(db, exclude...)
+		if err != nil {
+			return err
+		}
+		if diff := Diff(before, after); len(diff) > 0 {
+			return &RollbackError{ID: migration.ID, Diff: diff}
+		}
+		if err := m.MigrateTo(migration.ID); err != nil {
+			return fmt.Errorf("gormigratetest: migrating to %q again: %w", migration.ID, err)
+		}
+	}
+	return nil
+}
+
+// isApplied reports whether the migration with the given ID ran.
+func isApplied(m *gormigrate.Gormigrate, id string) (bool, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return false, err
+	}
+	for _, status := range statuses {
+		if status.ID == id {
+			return status.State == gormigrate.StateApplied, nil
+		}
+	}
+	return false, nil
+}
+
+// copyOptions returns a copy of options, or of the default options when nil,
+// since gormigrate.New sets the defaults of the options it is given.
+func copyOptions(options *gormigrate.Options) *gormigrate.Options {
+	if options == nil {
+		options = gormigrate.DefaultOptions
+	}
+	copied := *options
+	return &copied
+}
+
+// bookkeepingTables returns the tables gormigrate keeps its state in, once
+// gormigrate.New set the defaults of options.
+func bookkeepingTables(options *gormigrate.Options) []string {
+	return []string{options.TableName, options.TableName + "_layout", options.StatementsTableName, options.CheckpointTableName}
+}
@@ -0,0 +1,217 @@
+package gormigratetest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Schema is a snapshot of the tables of a database.
+type Schema struct {
+	// Tables are keyed by name.
+	Tables map[string]*Table
+}
+
+// Table is a snapshot of a table.
+type Table struct {
+	// Columns holds the type and nullability of each column, keyed by name.
+	Columns map[string]string
+	// Indexes holds the columns and uniqueness of each index, keyed by name.
+	Indexes map[string]string
+	// ForeignKeys holds the foreign keys, like "(person_id) REFERENCES people (id)".
+	ForeignKeys []string
+}
+
+// foreignKeyQueries select the column, referenced table and referenced column
+// of the foreign keys of a table, for each dialect supporting them.
+var foreignKeyQueries = map[string]string{
+	"sqlite": `SELECT "from", "table", "to" FROM pragma_foreign_key_list(?)`,
+	"postgres": `SELECT kcu.column_name, ccu.table_name, ccu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema
+		JOIN information_schema.constraint_column_usage ccu
+			ON ccu.constraint_name = tc.constraint_name AND ccu.table_schema = tc.table_schema
+		WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_name = ? AND tc.table_schema = CURRENT_SCHEMA()`,
+	"mysql": `SELECT column_name, referenced_table_name, referenced_column_name
+		FROM information_schema.key_column_usage
+		WHERE table_schema = DATABASE() AND table_name = ? AND referenced_table_name IS NOT NULL`,
+	"sqlserver": `SELECT COL_NAME(parent_object_id, parent_column_id),
+			OBJECT_NAME(referenced_object_id),
+			COL_NAME(referenced_object_id, referenced_column_id)
+		FROM sys.foreign_key_columns
+		WHERE parent_object_id = OBJECT_ID(?)`,
+}
+
+// Snapshot returns the schema of the database, through the introspection of
+// db.Migrator(). The excluded tables, usually the ones kept by gormigrate,
+// are left out, and so are the internal tables of sqlite.
+func Snapshot(db *gorm.DB, exclude ...string) (*Schema, error) {
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{Tables: make(map[string]*Table)}
+	for _, name := range tables {
+		if contains(exclude, name) || strings.HasPrefix(name, "sqlite_") {
+			continue
+		}
+		table, err := snapshotTable(db, name)
+		if err != nil {
+			return nil, err
+		}
+		schema.Tables[name] = table
+	}
+	return schema, nil
+}
+
+func snapshotTable(db *gorm.DB, name string) (*Table, error) {
+	migrator := db.Migrator()
+	table := &Table{Columns: make(map[string]string), Indexes: make(map[string]string)}
+
+	columnTypes, err := migrator.ColumnTypes(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, columnType := range columnTypes {
+		description, ok := columnType.ColumnType()
+		if !ok || description == "" {
+			description = columnType.DatabaseTypeName()
+		}
+		description = strings.ToLower(description)
+		if primaryKey, ok := columnType.PrimaryKey(); ok && primaryKey {
+			description += " PRIMARY KEY"
+		}
+		if nullable, ok := columnType.Nullable(); ok && !nullable {
+			description += " NOT NULL"
+		}
+		table.Columns[columnType.Name()] = description
+	}
+
+	indexes, err := migrator.GetIndexes(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		if primaryKey, _ := index.PrimaryKey(); primaryKey {
+			continue
+		}
+		description := "(" + strings.Join(index.Columns(), ", ") + ")"
+		if unique, _ := index.Unique(); unique {
+			description = "UNIQUE " + description
+		}
+		table.Indexes[index.Name()] = description
+	}
+
+	if query, ok := foreignKeyQueries[db.Dialector.Name()]; ok {
+		rows, err := db.Raw(query, name).Rows()
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var column, referencedTable, referencedColumn string
+			if err := rows.Scan(&column, &referencedTable, &referencedColumn); err != nil {
+				return nil, err
+			}
+			table.ForeignKeys = append(table.ForeignKeys, fmt.Sprintf("(%s) REFERENCES %s (%s)", column, referencedTable, referencedColumn))
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		sort.Strings(table.ForeignKeys)
+	}
+	return table, nil
+}
+
+// Diff returns the differences between the expected and actual schemas, one
+// per line, sorted by table: lines starting with "-" are missing from actual,
+// lines starting with "+" are only in actual, and lines starting with "~"
+// differ between both.
+func Diff(expected, actual *Schema) []string {
+	var diff []string
+	for _, name := range tableNames(expected.Tables, actual.Tables) {
+		expectedTable, inExpected := expected.Tables[name]
+		actualTable, inActual := actual.Tables[name]
+		switch {
+		case !inActual:
+			diff = append(diff, "- table "+name)
+		case !inExpected:
+			diff = append(diff, "+ table "+name)
+		default:
+			diff = append(diff, diffDescriptions("column "+name+".", expectedTable.Columns, actualTable.Columns)...)
+			diff = append(diff, diffDescriptions("index "+name+".", expectedTable.Indexes, actualTable.Indexes)...)
+			diff = append(diff, diffForeignKeys("foreign key "+name+" ", expectedTable.ForeignKeys, actualTable.ForeignKeys)...)
+		}
+	}
+	return diff
+}
+
+func diffDescriptions(prefix string, expected, actual map[string]string) []string {
+	var diff []string
+	for _, name := range sortedKeys(expected, actual) {
+		expectedDescription, inExpected := expected[name]
+		actualDescription, inActual := actual[name]
+		switch {
+		case !inActual:
+			diff = append(diff, fmt.Sprintf("- %s%s: %s", prefix, name, expectedDescription))
+		case !inExpected:
+			diff = append(diff, fmt.Sprintf("+ %s%s: %s", prefix, name, actualDescription))
+		case expectedDescription != actualDescription:
+			diff = append(diff, fmt.Sprintf("~ %s%s: %s -> %s", prefix, name, expectedDescription, actualDescription))
+		}
+	}
+	return diff
+}
+
+func diffForeignKeys(prefix string, expected, actual []string) []string {
+	var diff []string
+	for _, foreignKey := range expected {
+		if !contains(actual, foreignKey) {
+			diff = append(diff, "- "+prefix+foreignKey)
+		}
+	}
+	for _, foreignKey := range actual {
+		if !contains(expected, foreignKey) {
+			diff = append(diff, "+ "+prefix+foreignKey)
+		}
+	}
+	return diff
+}
+
+func tableNames(a, b map[string]*Table) []string {
+	names := make(map[string]string, len(a)+len(b))
+	for name := range a {
+		names[name] = name
+	}
+	for name := range b {
+		names[name] = name
+	}
+	return sortedKeys(names, nil)
+}
+
+func sortedKeys(a, b map[string]string) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
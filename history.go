@@ -0,0 +1,115 @@
+package gormigrate
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// History is the exported state of the migrations, see ExportHistory.
+type History struct {
+	// Migrations are the applied migrations, ordered by ID.
+	Migrations []*AppliedMigration `json:"migrations"`
+}
+
+// HistoryConflict is a migration whose record differs between an imported
+// history and the store.
+type HistoryConflict struct {
+	// ID is the migration identifier.
+	ID string
+	// Imported is the record of the imported history, nil if it has none.
+	Imported *AppliedMigration
+	// Recorded is the record of the store, nil if it has none.
+	Recorded *AppliedMigration
+}
+
+// ExportHistory writes the applied migrations to w as a JSON History, which
+// ImportHistory reads back, e.g. into a copy of the database made without
+// its migration table. Nothing is written to the database.
+func (g *Gormigrate) ExportHistory(w io.Writer) error {
+	g.tx = g.db
+	g.state = g.stateDatabase()
+	store := g.stateStore()
+	exists, err := store.Exists(g.state)
+	if err != nil {
+		return err
+	}
+
+	history := &History{Migrations: []*AppliedMigration{}}
+	if exists {
+		if history.Migrations, err = store.List(g.state); err != nil {
+			return err
+		}
+	}
+	sort.Slice(history.Migrations, func(i, j int) bool {
+		return history.Migrations[i].ID < history.Migrations[j].ID
+	})
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(history)
+}
+
+// ImportHistory records the migrations of the JSON History read from r as
+// applied, creating the migration table if needed. It fails with a
+// HistoryConflictError, without recording anything, when the store has
+// records that differ from the history: migrations recorded with another
+// checksum, or recorded but missing from the history. Migrations recorded
+// identically are left untouched.
+func (g *Gormigrate) ImportHistory(r io.Reader) error {
+	var history History
+	if err := json.NewDecoder(r).Decode(&history); err != nil {
+		return err
+	}
+	for _, m := range history.Migrations {
+		if len(m.ID) == 0 {
+			return ErrMissingID
+		}
+	}
+
+	unlock, err := g.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	g.begin()
+	defer g.rollback()
+
+	if err := g.createMigrationTableIfNotExists(); err != nil {
+		return err
+	}
+	recorded, err := g.stateStore().List(g.state)
+	if err != nil {
+		return err
+	}
+
+	imported := make(map[string]*AppliedMigration, len(history.Migrations))
+	for _, m := range history.Migrations {
+		imported[m.ID] = m
+	}
+	existing := make(map[string]*AppliedMigration, len(recorded))
+	var conflicts []*HistoryConflict
+	for _, m := range recorded {
+		existing[m.ID] = m
+		if i, ok := imported[m.ID]; !ok || i.Checksum != m.Checksum || i.Skipped != m.Skipped {
+			conflicts = append(conflicts, &HistoryConflict{ID: m.ID, Imported: i, Recorded: m})
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Slice(conflicts, func(i, j int) bool {
+			return conflicts[i].ID < conflicts[j].ID
+		})
+		return &HistoryConflictError{Conflicts: conflicts}
+	}
+
+	for _, m := range history.Migrations {
+		if _, ok := existing[m.ID]; ok {
+			continue
+		}
+		if err := g.insertMigration(m); err != nil {
+			return err
+		}
+	}
+	return g.commit()
+}
@@ -0,0 +1,152 @@
+package gormigrate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// IDFormat is a format of migration IDs, see Options.IDFormat.
+type IDFormat string
+
+const (
+	// IDFormatTimestamp IDs are UTC timestamps, like "20160830140000".
+	// Timestamps without seconds, like "201608301400", are accepted as well.
+	IDFormatTimestamp IDFormat = "timestamp"
+	// IDFormatSequence IDs are zero-padded sequence numbers, like "0042".
+	IDFormatSequence IDFormat = "sequence"
+	// IDFormatSemver IDs are semantic versions, like "1.4.0".
+	IDFormatSemver IDFormat = "semver"
+)
+
+const (
+	timestampIDLayout       = "20060102150405"
+	shortTimestampIDLayout  = "200601021504"
+	defaultSequenceIDLength = 4
+)
+
+var (
+	sequenceIDPattern = regexp.MustCompile(`^[0-9]+$`)
+	semverIDPattern   = regexp.MustCompile(`^(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)$`)
+)
+
+// Validate returns an *InvalidIDError if id is not in the format.
+func (f IDFormat) Validate(id string) error {
+	var valid bool
+	switch f {
+	case IDFormatTimestamp:
+		_, err := parseTimestampID(id)
+		valid = err == nil
+	case IDFormatSequence:
+		valid = sequenceIDPattern.MatchString(id)
+	case IDFormatSemver:
+		valid = semverIDPattern.MatchString(id)
+	default:
+		return ErrUnknownIDFormat
+	}
+	if !valid {
+		return &InvalidIDError{ID: id, Format: f}
+	}
+	return nil
+}
+
+// Next returns a new ID coming after the given IDs, which are in the format.
+// Timestamp IDs are based on now. Sequence IDs keep the length of the last ID,
+// and semver IDs increment its patch version.
+func (f IDFormat) Next(ids []string, now time.Time) (string, error) {
+	var last string
+	for _, id := range ids {
+		if err := f.Validate(id); err != nil {
+			return "", err
+		}
+		if last == "" || f.less(last, id) {
+			last = id
+		}
+	}
+
+	switch f {
+	case IDFormatTimestamp:
+		// Right after the last ID when it is not before now, e.g. when
+		// creating several migrations within a second
+		id := now.UTC().Format(timestampIDLayout)
+		if last != "" && !f.less(last, id) {
+			t, _ := parseTimestampID(last)
+			id = t.Add(time.Second).Format(timestampIDLayout)
+		}
+		return id, nil
+	case IDFormatSequence:
+		if last == "" {
+			return fmt.Sprintf("%0*d", defaultSequenceIDLength, 1), nil
+		}
+		n, err := strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%0*d", len(last), n+1), nil
+	case IDFormatSemver:
+		if last == "" {
+			return "1.0.0", nil
+		}
+		version := semverIDPattern.FindStringSubmatch(last)
+		patch, _ := strconv.Atoi(version[3])
+		return fmt.Sprintf("%s.%s.%d", version[1], version[2], patch+1), nil
+	default:
+		return "", ErrUnknownIDFormat
+	}
+}
+
+// less reports whether the ID a comes before b. IDs that are not in the
+// format are compared as strings.
+func (f IDFormat) less(a, b string) bool {
+	switch f {
+	case IDFormatTimestamp:
+		ta, errA := parseTimestampID(a)
+		tb, errB := parseTimestampID(b)
+		if errA == nil && errB == nil && !ta.Equal(tb) {
+			return ta.Before(tb)
+		}
+	case IDFormatSequence:
+		na, errA := strconv.ParseUint(a, 10, 64)
+		nb, errB := strconv.ParseUint(b, 10, 64)
+		if errA == nil && errB == nil && na != nb {
+			return na < nb
+		}
+	case IDFormatSemver:
+		va, vb := semverIDPattern.FindStringSubmatch(a), semverIDPattern.FindStringSubmatch(b)
+		if va != nil && vb != nil {
+			for i := 1; i <= 3; i++ {
+				na, _ := strconv.Atoi(va[i])
+				nb, _ := strconv.Atoi(vb[i])
+				if na != nb {
+					return na < nb
+				}
+			}
+		}
+	}
+	return a < b
+}
+
+func parseTimestampID(id string) (time.Time, error) {
+	if len(id) == len(shortTimestampIDLayout) {
+		return time.Parse(shortTimestampIDLayout, id)
+	}
+	return time.Parse(timestampIDLayout, id)
+}
+
+// checkIDs returns an error if an ID is longer than Options.IDColumnSize,
+// or not in Options.IDFormat when set. Repeatable migrations are named freely.
+func (g *Gormigrate) checkIDs() error {
+	for _, m := range g.migrations {
+		if len(m.ID) > g.options.IDColumnSize {
+			return &IDTooLongError{ID: m.ID, Size: g.options.IDColumnSize}
+		}
+		if g.options.IDFormat == "" || m.Repeatable {
+			continue
+		}
+		if err := g.options.IDFormat.Validate(m.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
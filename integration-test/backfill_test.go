@@ -0,0 +1,118 @@
+package gormigrate_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func TestBackfill(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		defer func() {
+			assert.NoError(t, db.Migrator().DropTable("migration_checkpoints"))
+		}()
+		require.NoError(t, db.AutoMigrate(&Person{}))
+		for i := 0; i < 25; i++ {
+			require.NoError(t, db.Create(&Person{Name: fmt.Sprintf("person %d", i)}).Error)
+		}
+
+		var (
+			failAfter = 1
+			batches   [][2]int64
+			progress  []gormigrate.BackfillProgress
+		)
+		m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{{
+			ID: "201608301500",
+			Backfill: &gormigrate.Backfill{
+				Table:     "people",
+				BatchSize: 10,
+				Batch: func(tx *gorm.DB, first, last int64) error {
+					if failAfter == 0 {
+						return errors.New("interrupted")
+					}
+					failAfter--
+					batches = append(batches, [2]int64{first, last})
+					return tx.Model(&Person{}).Where("id BETWEEN ? AND ?", first, last).Update("name", "backfilled").Error
+				},
+				Progress: func(p gormigrate.BackfillProgress) {
+					progress = append(progress, p)
+				},
+			},
+		}})
+
+		// The first run is interrupted after one batch
+		require.Error(t, m.Migrate())
+		assert.Equal(t, [][2]int64{{1, 10}}, batches)
+		assert.Equal(t, int64(0), tableCount(t, db, "migrations"))
+		assert.Equal(t, int64(1), tableCount(t, db, "migration_checkpoints"))
+
+		// The second run resumes after the last processed batch
+		failAfter = -1
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, [][2]int64{{1, 10}, {11, 20}, {21, 25}}, batches)
+		assert.Equal(t, []gormigrate.BackfillProgress{
+			{ID: "201608301500", Batches: 1, Rows: 10, LastKey: 10},
+			{ID: "201608301500", Batches: 1, Rows: 20, LastKey: 20},
+			{ID: "201608301500", Batches: 2, Rows: 25, LastKey: 25},
+		}, progress)
+		assert.Equal(t, int64(1), tableCount(t, db, "migrations"))
+		assert.Equal(t, int64(0), tableCount(t, db, "migration_checkpoints"))
+
+		var count int64
+		require.NoError(t, db.Model(&Person{}).Where("name = ?", "backfilled").Count(&count).Error)
+		assert.Equal(t, int64(25), count)
+	})
+}
+
+func TestBackfillWithTransaction(t *testing.T) {
+	dialects.withTransactionSupport().forEachDB(t, func(db *gorm.DB) {
+		defer func() {
+			assert.NoError(t, db.Migrator().DropTable("migration_checkpoints"))
+		}()
+		options := *gormigrate.DefaultOptions
+		options.UseTransaction = true
+		m := gormigrate.New(db, &options, []*gormigrate.Migration{
+			{
+				ID: "201608301400",
+				Migrate: func(tx *gorm.DB) error {
+					if err := tx.AutoMigrate(&Person{}); err != nil {
+						return err
+					}
+					return tx.Create(&[]*Person{{Name: "first"}, {Name: "second"}}).Error
+				},
+			},
+			{
+				ID: "201608301500",
+				Backfill: &gormigrate.Backfill{
+					Table: "people",
+					Batch: func(tx *gorm.DB, first, last int64) error {
+						return tx.Model(&Person{}).Where("id BETWEEN ? AND ?", first, last).Update("name", "backfilled").Error
+					},
+				},
+			},
+		})
+
+		// The batches see the table created by the previous migration
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, int64(2), tableCount(t, db, "migrations"))
+		var count int64
+		require.NoError(t, db.Model(&Person{}).Where("name = ?", "backfilled").Count(&count).Error)
+		assert.Equal(t, int64(2), count)
+	})
+}
+
+func TestBackfillInvalid(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{{
+			ID:       "201608301500",
+			Backfill: &gormigrate.Backfill{Table: "people"},
+		}})
+		assert.Equal(t, gormigrate.ErrInvalidBackfill, m.Migrate())
+	})
+}
@@ -0,0 +1,79 @@
+package gormigrate_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func TestReport(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, migrations)
+
+		require.NoError(t, m.Migrate())
+		report := m.Report()
+		require.Len(t, report.Migrations, 2)
+		assert.Equal(t, "201608301400", report.Migrations[0].ID)
+		assert.Equal(t, "201608301430", report.Migrations[1].ID)
+		assert.Equal(t, gormigrate.StepMigrate, report.Migrations[1].Step)
+		assert.Empty(t, report.Migrations[1].Statements)
+
+		require.NoError(t, m.RollbackLast())
+		report = m.Report()
+		require.Len(t, report.Migrations, 1)
+		assert.Equal(t, "201608301430", report.Migrations[0].ID)
+		assert.Equal(t, gormigrate.StepRollback, report.Migrations[0].Step)
+	})
+}
+
+func TestCaptureStatements(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		options := *gormigrate.DefaultOptions
+		options.CaptureStatements = true
+		options.StatementsTableName = "migration_statements"
+		defer func() {
+			assert.NoError(t, db.Migrator().DropTable("migration_statements"))
+		}()
+		m := gormigrate.New(db, &options, migrations)
+		// Callbacks are registered before running, not while the application
+		// may use db concurrently
+		assert.NotNil(t, db.Callback().Raw().Get("gormigrate:capture_after"))
+
+		require.NoError(t, m.Migrate())
+		report := m.Report()
+		require.Len(t, report.Migrations, 2)
+		require.NotEmpty(t, report.Migrations[0].Statements)
+		assert.Contains(t, report.Migrations[0].Statements[len(report.Migrations[0].Statements)-1].SQL, "people")
+		for _, statement := range report.Migrations[1].Statements {
+			assert.NotContains(t, statement.SQL, "people")
+			assert.NoError(t, statement.Err)
+		}
+
+		var bundle bytes.Buffer
+		require.NoError(t, report.WriteSQL(&bundle))
+		assert.Contains(t, bundle.String(), "-- Migration: 201608301430")
+		assert.Contains(t, bundle.String(), "CREATE TABLE")
+
+		require.NoError(t, m.RollbackLast())
+		assert.Contains(t, m.Report().Migrations[0].Statements[len(m.Report().Migrations[0].Statements)-1].SQL, "DROP TABLE")
+
+		var export bytes.Buffer
+		require.NoError(t, m.ExportStatements(&export))
+		assert.Contains(t, export.String(), "-- Migration: 201608301400")
+		assert.Contains(t, export.String(), "-- Migration: 201608301430")
+		assert.Contains(t, export.String(), "-- Rollback: 201608301430")
+		assert.Contains(t, export.String(), "DROP TABLE")
+	})
+}
+
+func TestExportStatementsWithoutTable(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, migrations)
+		assert.Equal(t, gormigrate.ErrNoStatementsTable, m.ExportStatements(&bytes.Buffer{}))
+	})
+}
@@ -0,0 +1,108 @@
+package gormigrate_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func TestCheck(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, migrations)
+
+		assert.Equal(t, gormigrate.ErrMissingMigrationTable, m.Check(nil))
+		assert.False(t, db.Migrator().HasTable("migrations"))
+
+		err := m.Check(&gormigrate.CheckOptions{AllowMissingTable: true})
+		var pendingErr *gormigrate.PendingMigrationsError
+		require.True(t, errors.As(err, &pendingErr))
+		assert.Equal(t, []string{"201608301400", "201608301430"}, pendingErr.IDs)
+		assert.NoError(t, m.Check(&gormigrate.CheckOptions{AllowMissingTable: true, AllowPending: true}))
+
+		require.NoError(t, m.MigrateTo("201608301400"))
+		err = m.Check(nil)
+		require.True(t, errors.As(err, &pendingErr))
+		assert.Equal(t, []string{"201608301430"}, pendingErr.IDs)
+
+		require.NoError(t, m.Migrate())
+		assert.NoError(t, m.Check(nil))
+	})
+}
+
+func TestCheckPreconditionFailed(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var runs int
+		m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{
+			preconditionMigration(gormigrate.PreconditionHalt, &runs),
+		})
+
+		// The migration did not run, whatever its precondition
+		err := m.Check(&gormigrate.CheckOptions{AllowMissingTable: true})
+		var pendingErr *gormigrate.PendingMigrationsError
+		require.True(t, errors.As(err, &pendingErr))
+		assert.Equal(t, []string{"201608301500"}, pendingErr.IDs)
+	})
+}
+
+func TestCheckUnknownMigrations(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		require.NoError(t, gormigrate.New(db, gormigrate.DefaultOptions, extendedMigrations).Migrate())
+		defer func() {
+			assert.NoError(t, db.Migrator().DropTable("books"))
+		}()
+
+		m := gormigrate.New(db, gormigrate.DefaultOptions, migrations)
+		err := m.Check(nil)
+		var unknownErr *gormigrate.UnknownMigrationsError
+		require.True(t, errors.As(err, &unknownErr))
+		assert.Equal(t, []string{"201807221927"}, unknownErr.IDs)
+		assert.NoError(t, m.Check(&gormigrate.CheckOptions{AllowUnknown: true}))
+	})
+}
+
+func TestCheckIDColumnChange(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		require.NoError(t, gormigrate.New(db, gormigrate.DefaultOptions, migrations).Migrate())
+
+		// The ID column is only renamed by runs, so checking must read it as it is
+		options := *gormigrate.DefaultOptions
+		options.IDColumnName = "version"
+		m := gormigrate.New(db, &options, migrations)
+		assert.NoError(t, m.Check(nil))
+		assert.Equal(t, gormigrate.StateApplied, statesOf(t, m)["201608301430"])
+		assert.False(t, db.Migrator().HasColumn("migrations", "version"))
+	})
+}
+
+func TestCheckBaselineLayout(t *testing.T) {
+	type migration struct {
+		ID string `gorm:"primaryKey;size:255"`
+	}
+
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		// The migration table of releases without checksums is only upgraded
+		// by runs, so checking must read it as it is
+		require.NoError(t, db.Table("migrations").AutoMigrate(&migration{}))
+		require.NoError(t, db.Table("migrations").Create(&migration{ID: "201608301400"}).Error)
+
+		m := gormigrate.New(db, gormigrate.DefaultOptions, migrations)
+		err := m.Check(nil)
+		var pendingErr *gormigrate.PendingMigrationsError
+		require.True(t, errors.As(err, &pendingErr))
+		assert.Equal(t, []string{"201608301430"}, pendingErr.IDs)
+
+		require.NoError(t, db.Table("migrations").Create(&migration{ID: "201608301430"}).Error)
+		assert.NoError(t, m.Check(nil))
+		assert.Equal(t, gormigrate.StateApplied, statesOf(t, m)["201608301430"])
+
+		var script strings.Builder
+		require.NoError(t, m.Script(&script))
+		assert.False(t, db.Migrator().HasColumn("migrations", "checksum"))
+	})
+}
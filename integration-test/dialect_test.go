@@ -0,0 +1,92 @@
+package gormigrate_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func TestDialectMigrate(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var ran string
+		m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{{
+			ID: "201608301400",
+			Migrate: func(tx *gorm.DB) error {
+				ran = "fallback"
+				return nil
+			},
+			DialectMigrate: map[string]gormigrate.MigrateFunc{
+				db.Dialector.Name(): func(tx *gorm.DB) error {
+					ran = tx.Dialector.Name()
+					return nil
+				},
+			},
+			DialectRollback: map[string]gormigrate.RollbackFunc{
+				db.Dialector.Name(): func(tx *gorm.DB) error {
+					ran = "rollback " + tx.Dialector.Name()
+					return nil
+				},
+			},
+		}})
+
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, db.Dialector.Name(), ran)
+		require.NoError(t, m.RollbackLast())
+		assert.Equal(t, "rollback "+db.Dialector.Name(), ran)
+	})
+}
+
+func TestDialectMigrateFallback(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var ran string
+		m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{{
+			ID: "201608301400",
+			Migrate: func(tx *gorm.DB) error {
+				ran = "fallback"
+				return nil
+			},
+			DialectMigrate: map[string]gormigrate.MigrateFunc{
+				"unknown": func(tx *gorm.DB) error {
+					ran = "unknown"
+					return nil
+				},
+			},
+			DialectRollback: map[string]gormigrate.RollbackFunc{
+				"unknown": func(tx *gorm.DB) error {
+					return nil
+				},
+			},
+		}})
+
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, "fallback", ran)
+
+		var dialectErr *gormigrate.UnsupportedDialectError
+		require.ErrorAs(t, m.RollbackLast(), &dialectErr)
+		assert.Equal(t, gormigrate.StepRollback, dialectErr.Step)
+		assert.Equal(t, db.Dialector.Name(), dialectErr.Dialect)
+	})
+}
+
+func TestDialectMigrateUnsupported(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{{
+			ID: "201608301400",
+			DialectMigrate: map[string]gormigrate.MigrateFunc{
+				"unknown": func(tx *gorm.DB) error {
+					return nil
+				},
+			},
+		}})
+
+		var dialectErr *gormigrate.UnsupportedDialectError
+		require.ErrorAs(t, m.Migrate(), &dialectErr)
+		assert.Equal(t, "201608301400", dialectErr.ID)
+		assert.Equal(t, gormigrate.StepMigrate, dialectErr.Step)
+		assert.Equal(t, int64(0), tableCount(t, db, "migrations"))
+	})
+}
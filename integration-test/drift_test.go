@@ -0,0 +1,49 @@
+package gormigrate_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+// driftedPerson is Person after fields were edited without a migration.
+type driftedPerson struct {
+	gorm.Model
+	Name string `gorm:"not null;index"`
+	Age  int
+}
+
+func (driftedPerson) TableName() string {
+	return "people"
+}
+
+func TestCheckDrift(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, migrations)
+		require.NoError(t, m.MigrateTo("201608301400"))
+
+		drifts, err := gormigrate.CheckDrift(db, &Person{})
+		require.NoError(t, err)
+		assert.Empty(t, drifts)
+
+		require.NoError(t, db.Exec("ALTER TABLE people ADD nickname varchar(255)").Error)
+		drifts, err = gormigrate.CheckDrift(db, &driftedPerson{}, &Pet{})
+		require.NoError(t, err)
+
+		kinds := make(map[string]gormigrate.DriftKind)
+		for _, drift := range drifts {
+			kinds[drift.Table+"."+drift.Name] = drift.Kind
+		}
+		assert.Equal(t, map[string]gormigrate.DriftKind{
+			"people.name":            gormigrate.DriftMismatchedColumn,
+			"people.age":             gormigrate.DriftMissingColumn,
+			"people.nickname":        gormigrate.DriftExtraColumn,
+			"people.idx_people_name": gormigrate.DriftMissingIndex,
+			"pets.":                  gormigrate.DriftMissingTable,
+		}, kinds)
+	})
+}
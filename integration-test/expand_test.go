@@ -0,0 +1,91 @@
+package gormigrate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func TestColumnChange(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		testColumnChange(t, db, false)
+	})
+}
+
+// TestColumnChangeWithTransaction runs the backfill migration, right after
+// the expand migration, with its changes committed.
+func TestColumnChangeWithTransaction(t *testing.T) {
+	dialects.withTransactionSupport().forEachDB(t, func(db *gorm.DB) {
+		testColumnChange(t, db, true)
+	})
+}
+
+func testColumnChange(t *testing.T, db *gorm.DB, useTransaction bool) {
+	defer func() {
+		assert.NoError(t, db.Migrator().DropTable("migration_checkpoints"))
+	}()
+	require.NoError(t, db.AutoMigrate(&Person{}))
+	require.NoError(t, db.Create(&Person{Name: "existing"}).Error)
+
+	change := &gormigrate.ColumnChange{
+		Table:     "people",
+		Column:    "name",
+		Type:      "varchar(255)",
+		NewColumn: "full_name",
+	}
+	options := *gormigrate.DefaultOptions
+	options.UseTransaction = useTransaction
+	m := gormigrate.New(db, &options, change.Migrations("201608301500"))
+
+	switch db.Dialector.Name() {
+	case "postgres", "mysql", "sqlite":
+	default:
+		var unsupported *gormigrate.UnsupportedDialectError
+		assert.True(t, errors.As(m.Migrate(), &unsupported))
+		return
+	}
+
+	// Expand and backfill
+	require.NoError(t, m.MigratePhase(gormigrate.PhasePreDeploy))
+	assert.Equal(t, []string{"existing"}, column(t, db, "full_name"))
+
+	// Writes of either column are copied to the other one
+	require.NoError(t, db.Exec("INSERT INTO people (name) VALUES (?)", "old code").Error)
+	require.NoError(t, db.Exec("INSERT INTO people (full_name) VALUES (?)", "new code").Error)
+	assert.Equal(t, []string{"existing", "old code", "new code"}, column(t, db, "name"))
+	assert.Equal(t, []string{"existing", "old code", "new code"}, column(t, db, "full_name"))
+	require.NoError(t, db.Exec("UPDATE people SET name = ? WHERE name = ?", "updated", "old code").Error)
+	require.NoError(t, db.Exec("UPDATE people SET full_name = ? WHERE full_name = ?", "changed", "new code").Error)
+	assert.Equal(t, []string{"existing", "updated", "changed"}, column(t, db, "name"))
+	assert.Equal(t, []string{"existing", "updated", "changed"}, column(t, db, "full_name"))
+
+	// Contract
+	require.NoError(t, m.Migrate())
+	assert.False(t, db.Migrator().HasColumn("people", "name"))
+	require.NoError(t, db.Exec("INSERT INTO people (full_name) VALUES (?)", "after contract").Error)
+
+	// Rolling back the contract migration restores the column and the triggers
+	require.NoError(t, m.RollbackLast())
+	assert.Equal(t, []string{"existing", "updated", "changed", "after contract"}, column(t, db, "name"))
+	require.NoError(t, db.Exec("UPDATE people SET full_name = ? WHERE full_name = ?", "synced", "after contract").Error)
+	assert.Equal(t, []string{"existing", "updated", "changed", "synced"}, column(t, db, "name"))
+
+	// Rolling back the backfill and expand migrations drops the new column
+	require.NoError(t, m.RollbackLast())
+	require.NoError(t, m.RollbackLast())
+	assert.False(t, db.Migrator().HasColumn("people", "full_name"))
+	require.NoError(t, db.Exec("INSERT INTO people (name) VALUES (?)", "without triggers").Error)
+	assert.Equal(t, int64(0), tableCount(t, db, "migrations"))
+}
+
+func column(t *testing.T, db *gorm.DB, name string) []string {
+	t.Helper()
+	var values []string
+	require.NoError(t, db.Table("people").Order("id").Pluck(name, &values).Error)
+	return values
+}
@@ -0,0 +1,44 @@
+package gormigrate_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func TestGenerate(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, migrations)
+		require.NoError(t, m.MigrateTo("201608301400"))
+
+		dir := t.TempDir()
+		options := &gormigrate.GenerateOptions{Dir: dir, Package: "migrations", Name: "add_person_age"}
+		_, err := gormigrate.Generate(db, options, &Person{})
+		assert.Equal(t, gormigrate.ErrNoSchemaChanges, err)
+
+		filename, err := gormigrate.Generate(db, options, &driftedPerson{}, &Pet{})
+		require.NoError(t, err)
+		assert.Equal(t, dir, filepath.Dir(filename))
+		assert.Regexp(t, `^\d{14}_add_person_age\.go$`, filepath.Base(filename))
+
+		content, err := os.ReadFile(filename)
+		require.NoError(t, err)
+		src := string(content)
+		assert.Contains(t, src, "package migrations")
+		assert.Contains(t, src, "type driftedPerson struct {")
+		assert.Contains(t, src, "type Pet struct {")
+		assert.Contains(t, src, `tx.Table("people").Migrator().AddColumn(&driftedPerson{}, "Age")`)
+		assert.Contains(t, src, `tx.Table("people").Migrator().CreateIndex(&driftedPerson{}, "idx_people_name")`)
+		assert.Contains(t, src, `tx.Table("pets").Migrator().CreateTable(&Pet{})`)
+		assert.Contains(t, src, `tx.Table("people").Migrator().DropColumn(&driftedPerson{}, "Age")`)
+		assert.Contains(t, src, `tx.Table("people").Migrator().DropIndex(&driftedPerson{}, "idx_people_name")`)
+		assert.Contains(t, src, `tx.Migrator().DropTable("pets")`)
+		assert.Contains(t, src, `// TODO: not generated, people: mismatched-column "name"`)
+	})
+}
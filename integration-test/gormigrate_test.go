@@ -39,7 +39,7 @@ func (dl dialectList) forEachDB(t *testing.T, fn func(gormdb *gorm.DB)) {
 			require.NoError(t, err, "Could not connect to database %s, %v", dia.name, err)
 
 			// ensure database is clean before running test
-			assert.NoError(t, db.Migrator().DropTable("migrations", "people", "pets"))
+			assert.NoError(t, db.Migrator().DropTable("migrations", "migrations_layout", "people", "pets"))
 
 			fn(db)
 		}(dia)
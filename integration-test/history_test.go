@@ -0,0 +1,57 @@
+package gormigrate_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func TestExportImportHistory(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var runs int
+		withRepeatable := append([]*gormigrate.Migration{repeatableMigration(gormigrate.Checksum("v1"), &runs)}, migrations...)
+		m := gormigrate.New(db, gormigrate.DefaultOptions, withRepeatable)
+		require.NoError(t, m.Migrate())
+
+		var exported bytes.Buffer
+		require.NoError(t, m.ExportHistory(&exported))
+		var history gormigrate.History
+		require.NoError(t, json.Unmarshal(exported.Bytes(), &history))
+		assert.Equal(t, []*gormigrate.AppliedMigration{
+			{ID: "201608301400"},
+			{ID: "201608301430"},
+			{ID: "people_names", Checksum: gormigrate.Checksum("v1")},
+		}, history.Migrations)
+
+		// A copy of the database without its migration table
+		require.NoError(t, db.Migrator().DropTable("migrations", "migrations_layout"))
+		require.NoError(t, m.ImportHistory(bytes.NewReader(exported.Bytes())))
+		require.NoError(t, m.ImportHistory(bytes.NewReader(exported.Bytes())))
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 1, runs)
+		assert.Equal(t, int64(3), tableCount(t, db, "migrations"))
+
+		changed := strings.Replace(exported.String(), gormigrate.Checksum("v1"), gormigrate.Checksum("v2"), 1)
+		err := m.ImportHistory(strings.NewReader(changed))
+		var conflictErr *gormigrate.HistoryConflictError
+		require.True(t, errors.As(err, &conflictErr))
+		require.Len(t, conflictErr.Conflicts, 1)
+		assert.Equal(t, "people_names", conflictErr.Conflicts[0].ID)
+		assert.Equal(t, gormigrate.Checksum("v2"), conflictErr.Conflicts[0].Imported.Checksum)
+
+		err = m.ImportHistory(strings.NewReader(`{"migrations": [{"id": "201608301400"}, {"id": "201807221927"}]}`))
+		require.True(t, errors.As(err, &conflictErr))
+		require.Len(t, conflictErr.Conflicts, 2)
+		assert.Equal(t, "201608301430", conflictErr.Conflicts[0].ID)
+		assert.Nil(t, conflictErr.Conflicts[0].Imported)
+		assert.Equal(t, int64(3), tableCount(t, db, "migrations"))
+	})
+}
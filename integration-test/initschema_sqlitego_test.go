@@ -0,0 +1,52 @@
+//go:build sqlitego
+
+package gormigrate_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2/gormigratetest"
+)
+
+// scratchDBs opens two fresh sqlite databases.
+func scratchDBs(t *testing.T) (*gorm.DB, *gorm.DB) {
+	dir := t.TempDir()
+	initDB, err := gorm.Open(sqlite.Open(filepath.Join(dir, "init.db")), &gorm.Config{})
+	require.NoError(t, err)
+	chainDB, err := gorm.Open(sqlite.Open(filepath.Join(dir, "chain.db")), &gorm.Config{})
+	require.NoError(t, err)
+	return initDB, chainDB
+}
+
+func TestCompareInitSchema(t *testing.T) {
+	initDB, chainDB := scratchDBs(t)
+	err := gormigratetest.CompareInitSchema(initDB, chainDB, nil, extendedMigrations, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&Person{}, &Pet{}, &Book{})
+	})
+	assert.NoError(t, err)
+}
+
+func TestCompareInitSchemaDrift(t *testing.T) {
+	type Person struct {
+		gorm.Model
+		Name string `gorm:"index"`
+	}
+
+	initDB, chainDB := scratchDBs(t)
+	err := gormigratetest.CompareInitSchema(initDB, chainDB, nil, extendedMigrations, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&Person{}, &Pet{})
+	})
+	var initSchemaErr *gormigratetest.InitSchemaError
+	require.True(t, errors.As(err, &initSchemaErr))
+	assert.Equal(t, []string{
+		"- table books",
+		"+ index people.idx_people_name: (name)",
+	}, initSchemaErr.Diff)
+}
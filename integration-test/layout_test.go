@@ -0,0 +1,103 @@
+package gormigrate_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func TestMigrationTableLayout(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		options := *gormigrate.DefaultOptions
+		require.NoError(t, gormigrate.New(db, &options, migrations).MigrateTo("201608301400"))
+		assert.Equal(t, int64(1), tableCount(t, db, "migrations_layout"))
+
+		// The ID column follows the options
+		options.IDColumnName = "migration_id"
+		options.IDColumnSize = 64
+		m := gormigrate.New(db, &options, migrations)
+		require.NoError(t, m.Migrate())
+		assert.True(t, db.Migrator().HasColumn("migrations", "migration_id"))
+		assert.False(t, db.Migrator().HasColumn("migrations", "id"))
+		assert.Equal(t, int64(2), tableCount(t, db, "migrations"))
+		assert.Equal(t, int64(1), tableCount(t, db, "migrations_layout"))
+		assert.Equal(t, map[string]gormigrate.MigrationState{
+			"201608301400": gormigrate.StateApplied,
+			"201608301430": gormigrate.StateApplied,
+		}, statesOf(t, m))
+
+		columnTypes, err := db.Migrator().ColumnTypes("migrations")
+		require.NoError(t, err)
+		for _, columnType := range columnTypes {
+			// SQLite ignores the size of text columns
+			if columnType.Name() == "migration_id" && db.Dialector.Name() != "sqlite" {
+				description, _ := columnType.ColumnType()
+				assert.Contains(t, description, "64")
+			}
+		}
+
+		require.NoError(t, m.RollbackLast())
+		assert.Equal(t, int64(1), tableCount(t, db, "migrations"))
+	})
+}
+
+// Migration tables of the layout without a status column are upgraded.
+func TestMigrationTableWithoutStatusColumn(t *testing.T) {
+	type migration struct {
+		ID       string `gorm:"primaryKey;size:255"`
+		Checksum string `gorm:"size:255"`
+	}
+	type layout struct {
+		Version      int
+		IDColumnName string `gorm:"size:255"`
+		IDColumnSize int
+	}
+
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		require.NoError(t, db.Table("migrations").AutoMigrate(&migration{}))
+		require.NoError(t, db.Table("migrations").Create(&migration{ID: "201608301400"}).Error)
+		require.NoError(t, db.Table("migrations_layout").AutoMigrate(&layout{}))
+		require.NoError(t, db.Table("migrations_layout").Create(&layout{Version: 2, IDColumnName: "id", IDColumnSize: 255}).Error)
+		require.NoError(t, db.AutoMigrate(&Person{}))
+
+		var runs int
+		m := gormigrate.New(db, gormigrate.DefaultOptions, append(migrations[:1:1],
+			preconditionMigration(gormigrate.PreconditionSkipAndMarkRan, &runs), migrations[1]))
+		assert.Equal(t, gormigrate.StateApplied, statesOf(t, m)["201608301400"])
+		require.NoError(t, m.Migrate())
+		assert.True(t, db.Migrator().HasColumn("migrations", "status"))
+		assert.Equal(t, map[string]gormigrate.MigrationState{
+			"201608301400": gormigrate.StateApplied,
+			"201608301500": gormigrate.StateSkippedByPrecondition,
+			"201608301430": gormigrate.StateApplied,
+		}, statesOf(t, m))
+	})
+}
+
+// The ID column of migration tables created before layouts were recorded is
+// found by its primary key, so that it can be renamed.
+func TestMigrationTableWithoutLayoutIDColumnChange(t *testing.T) {
+	type migration struct {
+		ID string `gorm:"primaryKey;size:255"`
+	}
+
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		require.NoError(t, db.Table("migrations").AutoMigrate(&migration{}))
+		require.NoError(t, db.Table("migrations").Create(&migration{ID: "201608301400"}).Error)
+		require.NoError(t, db.AutoMigrate(&Person{}))
+
+		options := *gormigrate.DefaultOptions
+		options.IDColumnName = "version"
+		m := gormigrate.New(db, &options, migrations)
+		assert.Equal(t, gormigrate.StateApplied, statesOf(t, m)["201608301400"])
+		require.NoError(t, m.Migrate())
+		assert.True(t, db.Migrator().HasColumn("migrations", "version"))
+		assert.False(t, db.Migrator().HasColumn("migrations", "id"))
+		assert.True(t, db.Migrator().HasTable(&Pet{}))
+		assert.Equal(t, int64(2), tableCount(t, db, "migrations"))
+	})
+}
@@ -0,0 +1,122 @@
+package gormigrate_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/go-gormigrate/gormigrate/v2/gormigrateprom"
+)
+
+type recordedMetrics struct {
+	steps   []string
+	pending int
+	head    string
+}
+
+func (m *recordedMetrics) ObserveMigration(id string, step gormigrate.Step, duration time.Duration, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "failed"
+	}
+	m.steps = append(m.steps, id+" "+string(step)+" "+outcome)
+}
+
+func (m *recordedMetrics) SetPending(count int) {
+	m.pending = count
+}
+
+func (m *recordedMetrics) SetHead(id string) {
+	m.head = id
+}
+
+func TestMetrics(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		metrics := &recordedMetrics{}
+		options := *gormigrate.DefaultOptions
+		options.Metrics = metrics
+		m := gormigrate.New(db, &options, extendedMigrations)
+
+		require.NoError(t, m.MigrateTo("201608301430"))
+		assert.Equal(t, []string{"201608301400 migrate ok", "201608301430 migrate ok"}, metrics.steps)
+		assert.Equal(t, 1, metrics.pending)
+		assert.Equal(t, "201608301430", metrics.head)
+
+		require.NoError(t, m.RollbackLast())
+		assert.Equal(t, "201608301430 rollback ok", metrics.steps[2])
+		assert.Equal(t, 2, metrics.pending)
+		assert.Equal(t, "201608301400", metrics.head)
+
+		failing := append(migrations, &gormigrate.Migration{
+			ID: "201608301500",
+			Migrate: func(tx *gorm.DB) error {
+				return errors.New("failure")
+			},
+		})
+		assert.Error(t, gormigrate.New(db, &options, failing).Migrate())
+		assert.Equal(t, "201608301500 migrate failed", metrics.steps[len(metrics.steps)-1])
+	})
+}
+
+func TestMetricsPreconditionFailed(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		metrics := &recordedMetrics{}
+		options := *gormigrate.DefaultOptions
+		options.UseTransaction = false
+		options.Metrics = metrics
+		var runs, checks int
+		blocked := preconditionMigration(gormigrate.PreconditionHalt, &runs)
+		precondition := blocked.Precondition
+		blocked.Precondition = func(tx *gorm.DB) (bool, error) {
+			checks++
+			return precondition(tx)
+		}
+		m := gormigrate.New(db, &options, append(migrations[:1:1], blocked))
+
+		var preconditionErr *gormigrate.PreconditionFailedError
+		require.ErrorAs(t, m.Migrate(), &preconditionErr)
+		// The blocked migration is pending, and the state is observed without
+		// evaluating its precondition again
+		assert.Equal(t, 1, metrics.pending)
+		assert.Equal(t, "201608301400", metrics.head)
+		assert.Equal(t, 1, checks)
+	})
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		registry := prometheus.NewRegistry()
+		metrics, err := gormigrateprom.New(registry)
+		require.NoError(t, err)
+		options := *gormigrate.DefaultOptions
+		options.Metrics = metrics
+		m := gormigrate.New(db, &options, extendedMigrations)
+
+		require.NoError(t, m.MigrateTo("201608301430"))
+		require.NoError(t, m.RollbackLast())
+		require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP gormigrate_head_info ID of the last applied migration.
+# TYPE gormigrate_head_info gauge
+gormigrate_head_info{id="201608301400"} 1
+# HELP gormigrate_migrations_applied_total Number of migrations applied.
+# TYPE gormigrate_migrations_applied_total counter
+gormigrate_migrations_applied_total 2
+# HELP gormigrate_migrations_rolled_back_total Number of migrations rolled back.
+# TYPE gormigrate_migrations_rolled_back_total counter
+gormigrate_migrations_rolled_back_total 1
+# HELP gormigrate_pending_migrations Number of pending migrations.
+# TYPE gormigrate_pending_migrations gauge
+gormigrate_pending_migrations 2
+`), "gormigrate_head_info", "gormigrate_migrations_applied_total", "gormigrate_migrations_rolled_back_total", "gormigrate_pending_migrations"))
+		// One histogram per step, whatever the number of migrations
+		assert.Equal(t, 2, testutil.CollectAndCount(registry, "gormigrate_migration_duration_seconds"))
+	})
+}
@@ -0,0 +1,68 @@
+package gormigrate_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+var phasedMigrations = []*gormigrate.Migration{
+	migrations[0],
+	{
+		ID:    "201608301415",
+		Phase: gormigrate.PhasePostDeploy,
+		Migrate: func(tx *gorm.DB) error {
+			type person struct {
+				Name string
+			}
+			return tx.Table("people").Migrator().DropColumn(&person{}, "Name")
+		},
+	},
+	migrations[1],
+}
+
+func TestMigratePhase(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, phasedMigrations)
+
+		pending, err := m.PendingByPhase()
+		require.NoError(t, err)
+		assert.Equal(t, map[gormigrate.Phase][]string{
+			gormigrate.PhasePreDeploy:  {"201608301400", "201608301430"},
+			gormigrate.PhasePostDeploy: {"201608301415"},
+		}, pending)
+
+		require.NoError(t, m.MigratePhase(gormigrate.PhasePreDeploy))
+		assert.True(t, db.Migrator().HasTable(&Pet{}))
+		assert.True(t, db.Migrator().HasColumn(&Person{}, "Name"))
+		assert.Equal(t, int64(2), tableCount(t, db, "migrations"))
+
+		pending, err = m.PendingByPhase()
+		require.NoError(t, err)
+		assert.Equal(t, map[gormigrate.Phase][]string{
+			gormigrate.PhasePostDeploy: {"201608301415"},
+		}, pending)
+
+		require.NoError(t, m.MigratePhase(gormigrate.PhasePostDeploy))
+		assert.False(t, db.Migrator().HasColumn(&Person{}, "Name"))
+		assert.Equal(t, int64(3), tableCount(t, db, "migrations"))
+	})
+}
+
+func TestMigratePhaseOrder(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, phasedMigrations)
+
+		var orderErr *gormigrate.PhaseOrderError
+		require.ErrorAs(t, m.MigratePhase(gormigrate.PhasePostDeploy), &orderErr)
+		assert.Equal(t, "201608301415", orderErr.ID)
+		assert.Equal(t, "201608301400", orderErr.PendingID)
+		assert.False(t, db.Migrator().HasTable(&Person{}))
+
+		assert.Equal(t, gormigrate.ErrMissingPhase, m.MigratePhase(""))
+	})
+}
@@ -0,0 +1,90 @@
+package gormigrate_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func preconditionMigration(action gormigrate.PreconditionAction, runs *int) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "201608301500",
+		Precondition: func(tx *gorm.DB) (bool, error) {
+			return tx.Migrator().HasTable("books"), nil
+		},
+		OnPreconditionFail: action,
+		Migrate: func(tx *gorm.DB) error {
+			*runs++
+			return nil
+		},
+	}
+}
+
+func TestPreconditionHalt(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var runs int
+		m := gormigrate.New(db, gormigrate.DefaultOptions, append(migrations[:1:1],
+			preconditionMigration(gormigrate.PreconditionHalt, &runs), migrations[1]))
+
+		err := m.Migrate()
+		var preconditionErr *gormigrate.PreconditionFailedError
+		require.ErrorAs(t, err, &preconditionErr)
+		assert.Equal(t, "201608301500", preconditionErr.ID)
+		assert.Equal(t, 0, runs)
+		assert.False(t, db.Migrator().HasTable(&Pet{}))
+		assert.Equal(t, gormigrate.StatePreconditionFailed, statesOf(t, m)["201608301500"])
+	})
+}
+
+func TestPreconditionSkip(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var runs int
+		m := gormigrate.New(db, gormigrate.DefaultOptions, append(migrations[:1:1],
+			preconditionMigration(gormigrate.PreconditionSkip, &runs), migrations[1]))
+
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 0, runs)
+		assert.True(t, db.Migrator().HasTable(&Pet{}))
+		assert.Equal(t, int64(2), tableCount(t, db, "migrations"))
+		assert.True(t, m.Report().Migrations[1].Skipped)
+		assert.Equal(t, gormigrate.StatePreconditionFailed, statesOf(t, m)["201608301500"])
+
+		// The migration is still pending, and runs once the precondition holds
+		require.NoError(t, db.AutoMigrate(&Book{}))
+		defer func() {
+			assert.NoError(t, db.Migrator().DropTable(&Book{}))
+		}()
+		assert.Equal(t, gormigrate.StatePending, statesOf(t, m)["201608301500"])
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 1, runs)
+		assert.Equal(t, gormigrate.StateApplied, statesOf(t, m)["201608301500"])
+	})
+}
+
+func TestPreconditionSkipAndMarkRan(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var runs int
+		m := gormigrate.New(db, gormigrate.DefaultOptions, append(migrations[:1:1],
+			preconditionMigration(gormigrate.PreconditionSkipAndMarkRan, &runs), migrations[1]))
+
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 0, runs)
+		assert.True(t, db.Migrator().HasTable(&Pet{}))
+		assert.Equal(t, int64(3), tableCount(t, db, "migrations"))
+		assert.True(t, m.Report().Migrations[1].Skipped)
+		assert.Equal(t, gormigrate.StateSkippedByPrecondition, statesOf(t, m)["201608301500"])
+		assert.Equal(t, gormigrate.StateApplied, statesOf(t, m)["201608301430"])
+
+		// The skip is kept along with the history
+		var history bytes.Buffer
+		require.NoError(t, m.ExportHistory(&history))
+		require.NoError(t, db.Migrator().DropTable("migrations", "migrations_layout"))
+		require.NoError(t, m.ImportHistory(&history))
+		assert.Equal(t, gormigrate.StateSkippedByPrecondition, statesOf(t, m)["201608301500"])
+	})
+}
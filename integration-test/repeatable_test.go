@@ -0,0 +1,102 @@
+package gormigrate_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func repeatableMigration(checksum string, runs *int) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID:         "people_names",
+		Repeatable: true,
+		Checksum:   checksum,
+		Migrate: func(tx *gorm.DB) error {
+			*runs++
+			return nil
+		},
+	}
+}
+
+func TestRepeatableMigration(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var runs int
+
+		// Repeatable migrations run after versioned migrations, wherever they are in the list
+		withRepeatable := []*gormigrate.Migration{repeatableMigration(gormigrate.Checksum("v1"), &runs)}
+		withRepeatable = append(withRepeatable, migrations...)
+		m := gormigrate.New(db, gormigrate.DefaultOptions, withRepeatable)
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 1, runs)
+		assert.Equal(t, "people_names", m.Report().Migrations[2].ID)
+		assert.Equal(t, int64(3), tableCount(t, db, "migrations"))
+
+		// Unchanged checksum: not run again
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 1, runs)
+
+		// Changed checksum: run again and record the new checksum
+		withRepeatable[0] = repeatableMigration(gormigrate.Checksum("v2"), &runs)
+		m = gormigrate.New(db, gormigrate.DefaultOptions, withRepeatable)
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 2, runs)
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 2, runs)
+		assert.Equal(t, int64(3), tableCount(t, db, "migrations"))
+
+		var checksum string
+		require.NoError(t, db.Table("migrations").Where("id = ?", "people_names").Pluck("checksum", &checksum).Error)
+		assert.Equal(t, gormigrate.Checksum("v2"), checksum)
+
+		// Repeatable migrations are not rolled back
+		require.NoError(t, m.RollbackLast())
+		assert.False(t, db.Migrator().HasTable(&Pet{}))
+		assert.Equal(t, gormigrate.ErrMigrationIDDoesNotExist, m.MigrateTo("people_names"))
+	})
+}
+
+func TestRepeatableMigrationOnlyRunsWhenMigratingToTheEnd(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var runs int
+		m := gormigrate.New(db, gormigrate.DefaultOptions, append([]*gormigrate.Migration{
+			repeatableMigration(gormigrate.Checksum("v1"), &runs),
+		}, migrations...))
+
+		require.NoError(t, m.MigrateTo("201608301400"))
+		assert.Equal(t, 0, runs)
+		require.NoError(t, m.MigrateTo("201608301430"))
+		assert.Equal(t, 1, runs)
+	})
+}
+
+func TestRepeatableMigrationMissingChecksum(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var runs int
+		m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{repeatableMigration("", &runs)})
+		assert.Equal(t, gormigrate.ErrMissingChecksum, m.Migrate())
+		assert.Equal(t, 0, runs)
+	})
+}
+
+// Migration tables created before checksums were recorded are upgraded.
+func TestMigrationTableWithoutChecksumColumn(t *testing.T) {
+	type migration struct {
+		ID string `gorm:"primaryKey;size:255"`
+	}
+
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		require.NoError(t, db.Table("migrations").AutoMigrate(&migration{}))
+		require.NoError(t, db.Table("migrations").Create(&migration{ID: "201608301400"}).Error)
+		require.NoError(t, db.AutoMigrate(&Person{}))
+
+		m := gormigrate.New(db, gormigrate.DefaultOptions, migrations)
+		require.NoError(t, m.Migrate())
+		assert.True(t, db.Migrator().HasColumn("migrations", "checksum"))
+		assert.True(t, db.Migrator().HasTable(&Pet{}))
+		assert.Equal(t, int64(2), tableCount(t, db, "migrations"))
+	})
+}
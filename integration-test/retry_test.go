@@ -0,0 +1,232 @@
+package gormigrate_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+type sqlStateError struct {
+	state string
+}
+
+func (e *sqlStateError) Error() string    { return "sqlstate " + e.state }
+func (e *sqlStateError) SQLState() string { return e.state }
+
+type numberedError struct {
+	Number uint16
+}
+
+func (e *numberedError) Error() string { return fmt.Sprintf("error %d", e.Number) }
+
+type sqlServerError struct {
+	number int32
+}
+
+func (e *sqlServerError) Error() string         { return fmt.Sprintf("mssql: error %d", e.number) }
+func (e *sqlServerError) SQLErrorNumber() int32 { return e.number }
+
+type sqliteError struct {
+	code int
+}
+
+func (e *sqliteError) Error() string { return fmt.Sprintf("sqlite: code %d", e.code) }
+func (e *sqliteError) Code() int     { return e.code }
+
+// tdsStreamError is like the StreamError of go-mssqldb.
+type tdsStreamError struct {
+	inner error
+}
+
+func (e tdsStreamError) Error() string { return "Invalid TDS stream: " + e.inner.Error() }
+
+func TestIsTransientError(t *testing.T) {
+	cases := []struct {
+		dialect   string
+		err       error
+		transient bool
+	}{
+		{"postgres", &sqlStateError{"40001"}, true},
+		{"postgres", fmt.Errorf("wrapped: %w", &sqlStateError{"40P01"}), true},
+		{"postgres", &sqlStateError{"55P03"}, true},
+		{"postgres", &sqlStateError{"23505"}, false},
+		{"mysql", &numberedError{Number: 1213}, true},
+		{"mysql", &numberedError{Number: 1205}, true},
+		{"mysql", &numberedError{Number: 1062}, false},
+		{"sqlserver", &sqlServerError{1205}, true},
+		{"sqlserver", &sqlServerError{2627}, false},
+		{"sqlite", &sqliteError{5}, true},
+		{"sqlite", &sqliteError{517}, true},
+		{"sqlite", &sqliteError{19}, false},
+		{"sqlite", &numberedError{Number: 1213}, false},
+		{"mysql", fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{"mysql", errors.New("invalid connection"), true},
+		{"mysql", fmt.Errorf("exec: %w", errors.New("invalid connection")), true},
+		{"mysql", &numberedError{Number: 1053}, true},
+		{"postgres", errors.New("invalid connection"), false},
+		{"sqlserver", &sqlServerError{10054}, true},
+		{"sqlserver", &sqlServerError{40613}, true},
+		{"sqlserver", tdsStreamError{io.ErrClosedPipe}, true},
+		{"sqlserver", fmt.Errorf("query: %w", io.EOF), true},
+		{"sqlite", io.EOF, false},
+		{"sqlserver", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"postgres", errors.New("syntax error"), false},
+		{"postgres", nil, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.transient, gormigrate.IsTransientError(c.dialect, c.err), "%s: %v", c.dialect, c.err)
+	}
+}
+
+// flakyMigration fails with err on its first failures attempts.
+func flakyMigration(id string, failures int, err error, attempts *int) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: id,
+		Migrate: func(tx *gorm.DB) error {
+			*attempts++
+			if *attempts <= failures {
+				return err
+			}
+			return tx.AutoMigrate(&Person{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("people")
+		},
+	}
+}
+
+func TestRetryTransientError(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		attempts := 0
+		options := *gormigrate.DefaultOptions
+		options.UseTransaction = true
+		options.Retry = &gormigrate.RetryPolicy{MaxAttempts: 3}
+		m := gormigrate.New(db, &options, []*gormigrate.Migration{
+			flakyMigration("201608301400", 2, &sqlStateError{"40001"}, &attempts),
+		})
+
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 3, attempts)
+		assert.True(t, db.Migrator().HasTable(&Person{}))
+		assert.Equal(t, int64(1), tableCount(t, db, "migrations"))
+	})
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		deadlock := &sqlStateError{"40P01"}
+		attempts := 0
+		options := *gormigrate.DefaultOptions
+		options.UseTransaction = true
+		options.Retry = &gormigrate.RetryPolicy{MaxAttempts: 2}
+		m := gormigrate.New(db, &options, []*gormigrate.Migration{
+			flakyMigration("201608301400", 5, deadlock, &attempts),
+		})
+
+		assert.Equal(t, deadlock, m.Migrate())
+		assert.Equal(t, 2, attempts)
+		assert.False(t, db.Migrator().HasTable(&Person{}))
+	})
+}
+
+func TestRetryIgnoresOtherErrors(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		failure := errors.New("not transient")
+		attempts := 0
+		options := *gormigrate.DefaultOptions
+		options.UseTransaction = true
+		options.Retry = &gormigrate.RetryPolicy{MaxAttempts: 3}
+		m := gormigrate.New(db, &options, []*gormigrate.Migration{
+			flakyMigration("201608301400", 1, failure, &attempts),
+		})
+
+		assert.Equal(t, failure, m.Migrate())
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestRetryPolicyOfMigration(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		attempts := 0
+		migration := flakyMigration("201608301400", 1, &sqlStateError{"40001"}, &attempts)
+		migration.Retry = &gormigrate.RetryPolicy{MaxAttempts: 2}
+		m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{migration})
+
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 2, attempts)
+
+		custom := errors.New("custom")
+		attempts = 0
+		options := *gormigrate.DefaultOptions
+		options.UseTransaction = true
+		options.Retry = &gormigrate.RetryPolicy{
+			MaxAttempts: 2,
+			IsTransient: func(dialect string, err error) bool {
+				return errors.Is(err, custom)
+			},
+		}
+		m = gormigrate.New(db, &options, []*gormigrate.Migration{
+			flakyMigration("201608301500", 1, custom, &attempts),
+		})
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 2, attempts)
+	})
+}
+
+func TestRetryWithoutTransaction(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		attempts := 0
+		options := *gormigrate.DefaultOptions
+		options.UseTransaction = false
+		options.Retry = &gormigrate.RetryPolicy{MaxAttempts: 3}
+		m := gormigrate.New(db, &options, []*gormigrate.Migration{
+			flakyMigration("201608301400", 1, &sqlStateError{"40001"}, &attempts),
+		})
+		assert.Equal(t, gormigrate.ErrRetryWithoutTransaction, m.Migrate())
+		assert.Equal(t, 0, attempts)
+
+		migration := flakyMigration("201608301400", 1, &sqlStateError{"40001"}, &attempts)
+		migration.Retry = &gormigrate.RetryPolicy{MaxAttempts: 2}
+		m = gormigrate.New(db, &gormigrate.Options{}, []*gormigrate.Migration{migration})
+		assert.Equal(t, gormigrate.ErrRetryWithoutTransaction, m.MigrateTo("201608301400"))
+		assert.Equal(t, 0, attempts)
+		assert.False(t, db.Migrator().HasTable(&Person{}))
+	})
+}
+
+func TestRetryStartsFromCleanTransaction(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		if db.Dialector.Name() == "mysql" {
+			t.Skip("MySQL does not support DDL in transactions")
+		}
+		var petRuns, attempts int
+		options := *gormigrate.DefaultOptions
+		options.UseTransaction = true
+		options.Retry = &gormigrate.RetryPolicy{MaxAttempts: 2}
+		m := gormigrate.New(db, &options, []*gormigrate.Migration{
+			{
+				ID: "201608301400",
+				Migrate: func(tx *gorm.DB) error {
+					petRuns++
+					return tx.AutoMigrate(&Pet{})
+				},
+			},
+			flakyMigration("201608301430", 1, &sqlStateError{"40001"}, &attempts),
+		})
+
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 2, petRuns)
+		assert.Equal(t, 2, attempts)
+		assert.True(t, db.Migrator().HasTable(&Pet{}))
+		assert.Equal(t, int64(2), tableCount(t, db, "migrations"))
+	})
+}
@@ -0,0 +1,81 @@
+package gormigrate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/go-gormigrate/gormigrate/v2/gormigratetest"
+)
+
+func TestRoundTrip(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		require.NoError(t, gormigratetest.RoundTrip(db, nil, migrations))
+		assert.True(t, db.Migrator().HasTable(&Pet{}))
+	})
+}
+
+func TestRoundTripSkippedMigrations(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var runs int
+		options := *gormigrate.DefaultOptions
+		options.ExcludeTags = []string{"seed"}
+		require.NoError(t, gormigratetest.RoundTrip(db, &options, append(migrations[:2:2], seedMigration(&runs))))
+		assert.Equal(t, 0, runs)
+		assert.True(t, db.Migrator().HasTable(&Pet{}))
+
+		require.NoError(t, db.Migrator().DropTable("migrations", "migrations_layout", "people", "pets"))
+		skipped := preconditionMigration(gormigrate.PreconditionSkip, &runs)
+		skipped.Rollback = func(tx *gorm.DB) error { return nil }
+		require.NoError(t, gormigratetest.RoundTrip(db, nil, append(migrations[:2:2], skipped)))
+		assert.Equal(t, 0, runs)
+		assert.True(t, db.Migrator().HasTable(&Pet{}))
+	})
+}
+
+func TestRoundTripBrokenRollback(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		type Person struct {
+			Name string
+			Age  int
+		}
+		broken := append(migrations, &gormigrate.Migration{
+			ID: "201608301500",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.Migrator().AddColumn(&Person{}, "Age")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return nil
+			},
+		})
+
+		err := gormigratetest.RoundTrip(db, nil, broken)
+		var rollbackErr *gormigratetest.RollbackError
+		require.True(t, errors.As(err, &rollbackErr))
+		assert.Equal(t, "201608301500", rollbackErr.ID)
+		assert.NoError(t, rollbackErr.Err)
+		require.Len(t, rollbackErr.Diff, 1)
+		assert.Regexp(t, `^\+ column people\.age: `, rollbackErr.Diff[0])
+	})
+}
+
+func TestRoundTripMissingRollback(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		missing := append(migrations, &gormigrate.Migration{
+			ID: "201608301500",
+			Migrate: func(tx *gorm.DB) error {
+				return nil
+			},
+		})
+
+		err := gormigratetest.RoundTrip(db, nil, missing)
+		var rollbackErr *gormigratetest.RollbackError
+		require.True(t, errors.As(err, &rollbackErr))
+		assert.Equal(t, "201608301500", rollbackErr.ID)
+		assert.Equal(t, gormigrate.ErrRollbackImpossible, rollbackErr.Err)
+	})
+}
@@ -0,0 +1,182 @@
+package gormigrate_test
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func TestScaffold(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	options := &gormigrate.ScaffoldOptions{Dir: dir, Name: "create_people", IDFormat: gormigrate.IDFormatSequence}
+
+	paths, err := gormigrate.Scaffold(options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "registry.go"), filepath.Join(dir, "0001_create_people.go")}, paths)
+
+	options.Name = "create_pets"
+	paths, err = gormigrate.Scaffold(options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "0002_create_pets.go")}, paths)
+
+	options.SQL = true
+	options.Name = "create_books"
+	paths, err = gormigrate.Scaffold(options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "registry_sql.go"),
+		filepath.Join(dir, "0003_create_books.up.sql"),
+		filepath.Join(dir, "0003_create_books.down.sql"),
+	}, paths)
+
+	options.Name = "create_authors"
+	paths, err = gormigrate.Scaffold(options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "0004_create_authors.up.sql"), filepath.Join(dir, "0004_create_authors.down.sql")}, paths)
+
+	fset := token.NewFileSet()
+	for _, name := range []string{"registry.go", "registry_sql.go", "0001_create_people.go", "0002_create_pets.go"} {
+		_, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		assert.NoError(t, err, name)
+	}
+
+	// The SQL files added after the Go migrations are registered as well
+	loader, err := parser.ParseFile(fset, filepath.Join(dir, "registry_sql.go"), nil, parser.ParseComments)
+	require.NoError(t, err)
+	var embedded, registered bool
+	for _, group := range loader.Comments {
+		for _, comment := range group.List {
+			embedded = embedded || comment.Text == "//go:embed *.sql"
+		}
+	}
+	ast.Inspect(loader, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallExpr); ok {
+			if fun, ok := call.Fun.(*ast.SelectorExpr); ok && fun.Sel.Name == "Register" {
+				receiver, ok := fun.X.(*ast.Ident)
+				registered = registered || ok && receiver.Name == "Migrations" && call.Ellipsis.IsValid()
+			}
+		}
+		return true
+	})
+	assert.True(t, embedded)
+	assert.True(t, registered)
+	sqlFiles, err := gormigrate.SQLMigrations(os.DirFS(dir))
+	require.NoError(t, err)
+	require.Len(t, sqlFiles, 2)
+	assert.Equal(t, "0003", sqlFiles[0].ID)
+	assert.Equal(t, "0004", sqlFiles[1].ID)
+	content, err := os.ReadFile(filepath.Join(dir, "0002_create_pets.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "package migrations")
+	assert.Contains(t, string(content), "Migrations.Register(&gormigrate.Migration{")
+	assert.Contains(t, string(content), `ID: "0002",`)
+
+	options.ID = "0001"
+	_, err = gormigrate.Scaffold(options)
+	var duplicated *gormigrate.DuplicatedIDError
+	assert.True(t, errors.As(err, &duplicated))
+
+	options.ID = "4"
+	options.IDFormat = gormigrate.IDFormatSemver
+	_, err = gormigrate.Scaffold(options)
+	var invalid *gormigrate.InvalidIDError
+	assert.True(t, errors.As(err, &invalid))
+
+	options.ID = ""
+	options.IDFormat = gormigrate.IDFormatTimestamp
+	options.IDColumnSize = 12
+	_, err = gormigrate.Scaffold(options)
+	var tooLong *gormigrate.IDTooLongError
+	assert.True(t, errors.As(err, &tooLong))
+}
+
+func TestIDFormat(t *testing.T) {
+	now := time.Date(2016, 8, 30, 14, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		format gormigrate.IDFormat
+		ids    []string
+		next   string
+	}{
+		{gormigrate.IDFormatTimestamp, nil, "20160830140000"},
+		{gormigrate.IDFormatTimestamp, []string{"201608301359"}, "20160830140000"},
+		{gormigrate.IDFormatTimestamp, []string{"201608301401"}, "20160830140101"},
+		{gormigrate.IDFormatSequence, nil, "0001"},
+		{gormigrate.IDFormatSequence, []string{"009", "010"}, "011"},
+		{gormigrate.IDFormatSemver, nil, "1.0.0"},
+		{gormigrate.IDFormatSemver, []string{"1.10.0", "1.9.3"}, "1.10.1"},
+	} {
+		next, err := tt.format.Next(tt.ids, now)
+		require.NoError(t, err)
+		assert.Equal(t, tt.next, next)
+	}
+
+	_, err := gormigrate.IDFormatSequence.Next([]string{"1.0.0"}, now)
+	var invalid *gormigrate.InvalidIDError
+	assert.True(t, errors.As(err, &invalid))
+
+	registry := &gormigrate.Registry{Format: gormigrate.IDFormatSemver}
+	registry.Register(&gormigrate.Migration{ID: "1.10.0"}, &gormigrate.Migration{ID: "1.9.0"}, &gormigrate.Migration{ID: "1.2.3"})
+	var ids []string
+	for _, m := range registry.Migrations() {
+		ids = append(ids, m.ID)
+	}
+	assert.Equal(t, []string{"1.2.3", "1.9.0", "1.10.0"}, ids)
+}
+
+func TestIDFormatOption(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		options := *gormigrate.DefaultOptions
+		options.IDFormat = gormigrate.IDFormatTimestamp
+		m := gormigrate.New(db, &options, append(migrations, &gormigrate.Migration{
+			ID:      "2016083015OO",
+			Migrate: func(tx *gorm.DB) error { return nil },
+		}))
+		var invalid *gormigrate.InvalidIDError
+		assert.True(t, errors.As(m.Migrate(), &invalid))
+		assert.Equal(t, "2016083015OO", invalid.ID)
+
+		options.IDFormat = ""
+		options.IDColumnSize = 10
+		var tooLong *gormigrate.IDTooLongError
+		assert.True(t, errors.As(m.Migrate(), &tooLong))
+		assert.False(t, db.Migrator().HasTable(&Person{}))
+	})
+}
+
+func TestSQLMigrations(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		sqlMigrations, err := gormigrate.SQLMigrations(fstest.MapFS{
+			"201608301400_create_people.up.sql":   {Data: []byte("CREATE TABLE people (id INT PRIMARY KEY, name VARCHAR(255))")},
+			"201608301400_create_people.down.sql": {Data: []byte("DROP TABLE people")},
+			"201608301430_create_pets.up.sql":     {Data: []byte("CREATE TABLE pets (id INT PRIMARY KEY)")},
+			"README.md":                           {Data: []byte("SQL migrations")},
+		})
+		require.NoError(t, err)
+		require.Len(t, sqlMigrations, 2)
+		assert.Equal(t, "201608301400", sqlMigrations[0].ID)
+		assert.Equal(t, "201608301430", sqlMigrations[1].ID)
+		assert.Nil(t, sqlMigrations[1].Rollback)
+
+		m := gormigrate.New(db, gormigrate.DefaultOptions, sqlMigrations)
+		require.NoError(t, m.Migrate())
+		assert.True(t, db.Migrator().HasTable("people"))
+		assert.True(t, db.Migrator().HasTable("pets"))
+
+		assert.Equal(t, gormigrate.ErrRollbackImpossible, m.RollbackLast())
+		require.NoError(t, gormigrate.New(db, gormigrate.DefaultOptions, sqlMigrations[:1]).RollbackLast())
+		assert.False(t, db.Migrator().HasTable("people"))
+		require.NoError(t, db.Migrator().DropTable("pets"))
+	})
+}
@@ -0,0 +1,92 @@
+package gormigrate_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func TestScript(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, migrations)
+
+		var script bytes.Buffer
+		require.NoError(t, m.Script(&script))
+		assert.Contains(t, script.String(), "-- Create migration table")
+		assert.Contains(t, script.String(), "-- Migration: 201608301400")
+		assert.Contains(t, script.String(), "-- Migration: 201608301430")
+		assert.Contains(t, script.String(), "INSERT INTO")
+
+		// Nothing has been written to the database
+		assert.False(t, db.Migrator().HasTable("migrations"))
+		assert.False(t, db.Migrator().HasTable(&Person{}))
+		assert.False(t, db.Migrator().HasTable(&Pet{}))
+	})
+}
+
+func TestScriptOnlyPendingMigrations(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, migrations)
+		require.NoError(t, m.MigrateTo("201608301400"))
+
+		var script, rollbackScript bytes.Buffer
+		require.NoError(t, m.Script(&script))
+		assert.NotContains(t, script.String(), "-- Create migration table")
+		assert.NotContains(t, script.String(), "201608301400")
+		assert.Contains(t, script.String(), "-- Migration: 201608301430")
+
+		require.NoError(t, m.RollbackScript(&rollbackScript))
+		assert.NotContains(t, rollbackScript.String(), "201608301400")
+		assert.Contains(t, rollbackScript.String(), "-- Rollback: 201608301430")
+		assert.Contains(t, rollbackScript.String(), "DROP TABLE")
+
+		assert.False(t, db.Migrator().HasTable(&Pet{}))
+		assert.Equal(t, int64(1), tableCount(t, db, "migrations"))
+	})
+}
+
+func TestScriptQueryRow(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		require.NoError(t, gormigrate.New(db, gormigrate.DefaultOptions, migrations[:1]).Migrate())
+		require.NoError(t, db.Create(&Person{Name: "Alice"}).Error)
+
+		var count int64
+		m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{{
+			ID: "201608301430",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.Raw("SELECT COUNT(*) FROM people").Row().Scan(&count); err != nil {
+					return err
+				}
+				var id uint
+				return tx.Raw("UPDATE people SET name = ? RETURNING id", "Bob").Row().Scan(&id)
+			},
+		}})
+
+		assert.Equal(t, gormigrate.ErrScriptUnsupportedQuery, m.Script(&bytes.Buffer{}))
+		assert.Equal(t, int64(1), count)
+		var person Person
+		require.NoError(t, db.First(&person).Error)
+		assert.Equal(t, "Alice", person.Name)
+	})
+}
+
+func TestRollbackScriptWithInitSchema(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, migrations)
+		m.InitSchema(func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&Person{}, &Pet{})
+		})
+
+		var script bytes.Buffer
+		require.NoError(t, m.Script(&script))
+		assert.Contains(t, script.String(), "-- Init schema: SCHEMA_INIT")
+		assert.False(t, db.Migrator().HasTable(&Person{}))
+
+		assert.Equal(t, gormigrate.ErrRollbackImpossible, m.RollbackScript(&bytes.Buffer{}))
+	})
+}
@@ -0,0 +1,41 @@
+//go:build sqlitego
+
+package gormigrate_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func TestStateDB(t *testing.T) {
+	for _, useTransaction := range []bool{false, true} {
+		db, stateDB := scratchDBs(t)
+		options := *gormigrate.DefaultOptions
+		options.UseTransaction = useTransaction
+		m := gormigrate.New(db, &options, migrations)
+		m.UseStateDB(stateDB)
+
+		require.NoError(t, m.Migrate())
+		assert.True(t, db.Migrator().HasTable(&Person{}))
+		assert.True(t, db.Migrator().HasTable(&Pet{}))
+		assert.False(t, db.Migrator().HasTable("migrations"))
+		assert.False(t, stateDB.Migrator().HasTable(&Person{}))
+		assert.Equal(t, int64(2), tableCount(t, stateDB, "migrations"))
+
+		assert.Equal(t, map[string]gormigrate.MigrationState{
+			"201608301400": gormigrate.StateApplied,
+			"201608301430": gormigrate.StateApplied,
+		}, statesOf(t, m))
+
+		require.NoError(t, m.RollbackLast())
+		assert.False(t, db.Migrator().HasTable(&Pet{}))
+		assert.Equal(t, int64(1), tableCount(t, stateDB, "migrations"))
+
+		assert.Equal(t, gormigrate.ErrScriptStateDB, m.Script(&bytes.Buffer{}))
+	}
+}
@@ -0,0 +1,101 @@
+package gormigrate_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func seedMigration(runs *int) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID:   "201608301500",
+		Tags: []string{"seed"},
+		Migrate: func(tx *gorm.DB) error {
+			*runs++
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return nil
+		},
+	}
+}
+
+func statesOf(t *testing.T, m *gormigrate.Gormigrate) map[string]gormigrate.MigrationState {
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	states := make(map[string]gormigrate.MigrationState, len(statuses))
+	for _, status := range statuses {
+		states[status.ID] = status.State
+	}
+	return states
+}
+
+func TestStatus(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, migrations)
+
+		assert.Equal(t, map[string]gormigrate.MigrationState{
+			"201608301400": gormigrate.StatePending,
+			"201608301430": gormigrate.StatePending,
+		}, statesOf(t, m))
+		assert.False(t, db.Migrator().HasTable("migrations"))
+
+		require.NoError(t, m.MigrateTo("201608301400"))
+		assert.Equal(t, map[string]gormigrate.MigrationState{
+			"201608301400": gormigrate.StateApplied,
+			"201608301430": gormigrate.StatePending,
+		}, statesOf(t, m))
+	})
+}
+
+func TestExcludeTags(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var runs int
+		options := *gormigrate.DefaultOptions
+		options.ExcludeTags = []string{"seed"}
+		options.ValidateUnknownMigrations = true
+		m := gormigrate.New(db, &options, append(migrations[:1:1], seedMigration(&runs), migrations[1]))
+
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 0, runs)
+		assert.Equal(t, int64(2), tableCount(t, db, "migrations"))
+		assert.Equal(t, gormigrate.StateSkippedByFilter, statesOf(t, m)["201608301500"])
+
+		// Rolling back does not touch the filtered migration
+		require.NoError(t, m.RollbackTo("201608301400"))
+		assert.Equal(t, int64(1), tableCount(t, db, "migrations"))
+
+		// Migrating to the filtered migration stops there
+		require.NoError(t, m.MigrateTo("201608301500"))
+		assert.Equal(t, 0, runs)
+		assert.Equal(t, int64(1), tableCount(t, db, "migrations"))
+		assert.False(t, db.Migrator().HasTable(&Pet{}))
+	})
+}
+
+func TestIncludeTags(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var runs int
+		withSeed := append(migrations[:1:1], seedMigration(&runs), migrations[1])
+
+		options := *gormigrate.DefaultOptions
+		options.IncludeTags = []string{"seed"}
+		m := gormigrate.New(db, &options, withSeed)
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 1, runs)
+		assert.Equal(t, int64(3), tableCount(t, db, "migrations"))
+
+		// The seed migration ran, but is not counted as unknown when filtered out
+		options = *gormigrate.DefaultOptions
+		options.IncludeTags = []string{"analytics"}
+		options.ValidateUnknownMigrations = true
+		m = gormigrate.New(db, &options, withSeed)
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, gormigrate.StateSkippedByFilter, statesOf(t, m)["201608301500"])
+		assert.Equal(t, gormigrate.StateApplied, statesOf(t, m)["201608301430"])
+	})
+}
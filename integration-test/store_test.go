@@ -0,0 +1,106 @@
+package gormigrate_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func appliedIDs(t *testing.T, store gormigrate.Store) map[string]string {
+	applied, err := store.List(nil)
+	require.NoError(t, err)
+	ids := make(map[string]string, len(applied))
+	for _, m := range applied {
+		ids[m.ID] = m.Checksum
+	}
+	return ids
+}
+
+func TestMemoryStore(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		var runs int
+		store := &gormigrate.MemoryStore{}
+		options := *gormigrate.DefaultOptions
+		options.Store = store
+		withRepeatable := append([]*gormigrate.Migration{repeatableMigration(gormigrate.Checksum("v1"), &runs)}, migrations...)
+
+		m := gormigrate.New(db, &options, withRepeatable)
+		require.NoError(t, m.Migrate())
+		assert.True(t, db.Migrator().HasTable(&Pet{}))
+		assert.False(t, db.Migrator().HasTable("migrations"))
+		assert.Equal(t, map[string]string{
+			"people_names": gormigrate.Checksum("v1"),
+			"201608301400": "",
+			"201608301430": "",
+		}, appliedIDs(t, store))
+
+		withRepeatable[0] = repeatableMigration(gormigrate.Checksum("v2"), &runs)
+		require.NoError(t, gormigrate.New(db, &options, withRepeatable).Migrate())
+		assert.Equal(t, 2, runs)
+		assert.Equal(t, gormigrate.Checksum("v2"), appliedIDs(t, store)["people_names"])
+
+		require.NoError(t, m.RollbackLast())
+		assert.False(t, db.Migrator().HasTable(&Pet{}))
+		assert.NotContains(t, appliedIDs(t, store), "201608301430")
+
+		assert.Equal(t, gormigrate.ErrScriptStore, m.Script(&bytes.Buffer{}))
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		path := filepath.Join(t.TempDir(), "migrations.json")
+		options := *gormigrate.DefaultOptions
+		options.Store = &gormigrate.FileStore{Path: path}
+
+		m := gormigrate.New(db, &options, migrations)
+		assert.Equal(t, map[string]gormigrate.MigrationState{
+			"201608301400": gormigrate.StatePending,
+			"201608301430": gormigrate.StatePending,
+		}, statesOf(t, m))
+		require.NoError(t, m.Migrate())
+		assert.False(t, db.Migrator().HasTable("migrations"))
+
+		// The state is read back from the file
+		options.Store = &gormigrate.FileStore{Path: path}
+		m = gormigrate.New(db, &options, migrations)
+		assert.Equal(t, map[string]gormigrate.MigrationState{
+			"201608301400": gormigrate.StateApplied,
+			"201608301430": gormigrate.StateApplied,
+		}, statesOf(t, m))
+
+		require.NoError(t, m.RollbackTo("201608301400"))
+		assert.Equal(t, map[string]string{"201608301400": ""}, appliedIDs(t, options.Store))
+	})
+}
+
+func TestAdvisoryLock(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+		sqlDB.SetMaxOpenConns(1)
+		defer sqlDB.SetMaxOpenConns(0)
+
+		// Runs are not locked by default, so a single connection is enough
+		require.NoError(t, gormigrate.New(db, gormigrate.DefaultOptions, migrations).MigrateTo("201608301400"))
+
+		options := *gormigrate.DefaultOptions
+		options.AdvisoryLock = true
+		m := gormigrate.New(db, &options, migrations)
+		if db.Dialector.Name() == "sqlite" {
+			assert.NoError(t, m.Migrate())
+			return
+		}
+		assert.Equal(t, gormigrate.ErrLockSingleConnection, m.Migrate())
+
+		sqlDB.SetMaxOpenConns(2)
+		require.NoError(t, m.Migrate())
+		assert.True(t, db.Migrator().HasTable(&Pet{}))
+	})
+}
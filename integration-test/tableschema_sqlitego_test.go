@@ -0,0 +1,43 @@
+//go:build sqlitego
+
+package gormigrate_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func TestTableSchemaAttachedDatabase(t *testing.T) {
+	dir := t.TempDir()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "main.db")), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// Attached databases belong to the connection
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.Exec(fmt.Sprintf("ATTACH DATABASE '%s' AS ops", filepath.Join(dir, "ops.db"))).Error)
+
+	// The migration table of the main database must not be mistaken for the one of ops
+	require.NoError(t, gormigrate.New(db, gormigrate.DefaultOptions, migrations[:1]).Migrate())
+
+	options := *gormigrate.DefaultOptions
+	options.TableSchema = "ops"
+	m := gormigrate.New(db, &options, migrations)
+	require.NoError(t, m.Migrate())
+	assert.Equal(t, int64(2), tableCount(t, db, "ops.migrations"))
+	assert.Equal(t, int64(1), tableCount(t, db, "ops.migrations_layout"))
+	assert.Equal(t, int64(1), tableCount(t, db, "migrations"))
+	assert.Equal(t, int64(1), tableCount(t, db, "migrations_layout"))
+
+	require.NoError(t, m.RollbackLast())
+	assert.Equal(t, int64(1), tableCount(t, db, "ops.migrations"))
+	assert.Equal(t, int64(1), tableCount(t, db, "migrations"))
+}
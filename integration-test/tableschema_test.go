@@ -0,0 +1,86 @@
+package gormigrate_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+// defaultSchema returns the schema new tables are created in.
+func defaultSchema(t *testing.T, db *gorm.DB) string {
+	switch db.Dialector.Name() {
+	case "sqlite":
+		return "main"
+	case "sqlserver":
+		return "dbo"
+	case "mysql":
+		var database string
+		require.NoError(t, db.Raw("SELECT DATABASE()").Scan(&database).Error)
+		return database
+	}
+	return "public"
+}
+
+func TestTableSchema(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		defer func() {
+			assert.NoError(t, db.Migrator().DropTable("schema_migrations", "schema_migrations_layout"))
+		}()
+		tableSchema := defaultSchema(t, db)
+
+		options := *gormigrate.DefaultOptions
+		options.TableName = tableSchema + ".schema_migrations"
+		m := gormigrate.New(db, &options, migrations)
+		require.NoError(t, m.MigrateTo("201608301400"))
+		assert.True(t, db.Migrator().HasTable("schema_migrations"))
+		assert.Equal(t, int64(1), tableCount(t, db, "schema_migrations"))
+
+		options.TableName = "schema_migrations"
+		options.TableSchema = tableSchema
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, int64(2), tableCount(t, db, "schema_migrations"))
+		assert.False(t, db.Migrator().HasTable("migrations"))
+	})
+}
+
+func TestCreateTableSchema(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		options := *gormigrate.DefaultOptions
+		options.TableSchema = "gormigrate_ops"
+		options.CreateTableSchema = true
+		m := gormigrate.New(db, &options, migrations)
+
+		if db.Dialector.Name() == "sqlite" {
+			assert.Equal(t, gormigrate.ErrCreateSchemaUnsupported, m.Migrate())
+			return
+		}
+		defer func() {
+			assert.NoError(t, db.Migrator().DropTable("gormigrate_ops.migrations", "gormigrate_ops.migrations_layout"))
+		}()
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, int64(2), tableCount(t, db, "gormigrate_ops.migrations"))
+		assert.False(t, db.Migrator().HasTable("migrations"))
+	})
+}
+
+func TestUseTablePrefix(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		// The session shares the connections of db, which matters for in-memory databases
+		prefixed := db.Session(&gorm.Session{NewDB: true})
+		prefixed.Config.NamingStrategy = schema.NamingStrategy{TablePrefix: "app_"}
+		defer func() {
+			assert.NoError(t, db.Migrator().DropTable("app_migrations", "app_migrations_layout"))
+		}()
+
+		options := *gormigrate.DefaultOptions
+		options.UseTablePrefix = true
+		require.NoError(t, gormigrate.New(prefixed, &options, migrations).Migrate())
+		assert.Equal(t, int64(2), tableCount(t, db, "app_migrations"))
+		assert.False(t, db.Migrator().HasTable("migrations"))
+	})
+}
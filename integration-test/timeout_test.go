@@ -0,0 +1,132 @@
+package gormigrate_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+type timeoutQuery struct{ query, expected string }
+
+// lockTimeoutQueries read the lock timeout of the session, which is expected
+// to be 1500ms once set by gormigrate.
+var lockTimeoutQueries = map[string]timeoutQuery{
+	"postgres":  {"SELECT current_setting('lock_timeout')", "1500ms"},
+	"mysql":     {"SELECT @@SESSION.innodb_lock_wait_timeout", "2"},
+	"sqlserver": {"SELECT @@LOCK_TIMEOUT", "1500"},
+	"sqlite":    {"PRAGMA busy_timeout", "1500"},
+}
+
+// statementTimeoutQueries read the statement timeout of the session, which is
+// expected to be 2500ms once set by gormigrate.
+var statementTimeoutQueries = map[string]timeoutQuery{
+	"postgres": {"SELECT current_setting('statement_timeout')", "2500ms"},
+	"mysql":    {"SELECT @@SESSION.max_execution_time", "2500"},
+	"mariadb":  {"SELECT CAST(@@SESSION.max_statement_time * 1000 AS SIGNED)", "2500"},
+}
+
+// flavor returns the dialect of db, telling MariaDB apart from MySQL.
+func flavor(t *testing.T, db *gorm.DB) string {
+	if db.Dialector.Name() != "mysql" {
+		return db.Dialector.Name()
+	}
+	var version string
+	require.NoError(t, db.Raw("SELECT VERSION()").Scan(&version).Error)
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		return "mariadb"
+	}
+	return "mysql"
+}
+
+// timeoutProbe returns a migration recording a timeout of its session.
+func timeoutProbe(id, query string, timeout *string) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: id,
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Raw(query).Scan(timeout).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Raw(query).Scan(timeout).Error
+		},
+	}
+}
+
+// assertTimeoutReset checks that no idle connection of the pool kept the
+// timeout, holding them all so that each one is read.
+func assertTimeoutReset(t *testing.T, db *gorm.DB, probe timeoutQuery) {
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	ctx := context.Background()
+	var conns []*sql.Conn
+	defer func() {
+		for _, conn := range conns {
+			assert.NoError(t, conn.Close())
+		}
+	}()
+	for i := sqlDB.Stats().Idle; i > 0; i-- {
+		conn, err := sqlDB.Conn(ctx)
+		require.NoError(t, err)
+		conns = append(conns, conn)
+		var timeout string
+		require.NoError(t, conn.QueryRowContext(ctx, probe.query).Scan(&timeout))
+		assert.NotEqual(t, probe.expected, timeout)
+	}
+}
+
+func TestLockTimeout(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		probe := lockTimeoutQueries[db.Dialector.Name()]
+		for _, useTransaction := range []bool{false, true} {
+			require.NoError(t, db.Migrator().DropTable("migrations", "migrations_layout"))
+
+			var first, second, third string
+			options := *gormigrate.DefaultOptions
+			options.UseTransaction = useTransaction
+			options.LockTimeout = 1500 * time.Millisecond
+			withoutTimeout := timeoutProbe("201608301430", probe.query, &second)
+			withoutTimeout.LockTimeout = -1
+			m := gormigrate.New(db, &options, []*gormigrate.Migration{
+				timeoutProbe("201608301400", probe.query, &first),
+				withoutTimeout,
+				timeoutProbe("201608301500", probe.query, &third),
+			})
+
+			require.NoError(t, m.Migrate())
+			assert.Equal(t, probe.expected, first)
+			assert.NotEqual(t, probe.expected, second)
+			assert.Equal(t, probe.expected, third)
+			assertTimeoutReset(t, db, probe)
+
+			third = ""
+			require.NoError(t, m.RollbackLast())
+			assert.Equal(t, probe.expected, third)
+			assertTimeoutReset(t, db, probe)
+		}
+	})
+}
+
+func TestStatementTimeout(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		probe, ok := statementTimeoutQueries[flavor(t, db)]
+		var timeout string
+		migration := timeoutProbe("201608301400", probe.query, &timeout)
+		migration.StatementTimeout = 2500 * time.Millisecond
+		m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{migration})
+
+		if !ok {
+			assert.Equal(t, gormigrate.ErrTimeoutUnsupported, m.Migrate())
+			return
+		}
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, probe.expected, timeout)
+		assertTimeoutReset(t, db, probe)
+	})
+}
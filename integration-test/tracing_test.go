@@ -0,0 +1,69 @@
+package gormigrate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) string {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value.AsString()
+		}
+	}
+	return ""
+}
+
+func TestTracing(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		exporter := tracetest.NewInMemoryExporter()
+		options := *gormigrate.DefaultOptions
+		options.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+		var migrationSpan trace.SpanContext
+		traced := append(migrations, &gormigrate.Migration{
+			ID: "201608301500",
+			Migrate: func(tx *gorm.DB) error {
+				migrationSpan = trace.SpanContextFromContext(tx.Statement.Context)
+				return nil
+			},
+		})
+		require.NoError(t, gormigrate.New(db, &options, traced).Migrate())
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 4)
+		run := spans[len(spans)-1]
+		assert.Equal(t, "gormigrate.Migrate", run.Name)
+		assert.Equal(t, "201608301500", spanAttribute(run, gormigrate.MigrationIDKey))
+		for i, id := range []string{"201608301400", "201608301430", "201608301500"} {
+			assert.Equal(t, "gormigrate.Migration", spans[i].Name)
+			assert.Equal(t, run.SpanContext.SpanID(), spans[i].Parent.SpanID())
+			assert.Equal(t, id, spanAttribute(spans[i], gormigrate.MigrationIDKey))
+			assert.Equal(t, "migrate", spanAttribute(spans[i], gormigrate.StepKey))
+			assert.NotEmpty(t, spanAttribute(spans[i], gormigrate.DBSystemKey))
+		}
+		assert.Equal(t, spans[2].SpanContext.SpanID(), migrationSpan.SpanID())
+
+		exporter.Reset()
+		failure := errors.New("rollback failure")
+		traced[2].Rollback = func(tx *gorm.DB) error { return failure }
+		assert.Equal(t, failure, gormigrate.New(db, &options, traced).RollbackLast())
+		spans = exporter.GetSpans()
+		require.Len(t, spans, 2)
+		assert.Equal(t, "rollback", spanAttribute(spans[0], gormigrate.StepKey))
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+		assert.Equal(t, "gormigrate.RollbackLast", spans[1].Name)
+		assert.Equal(t, codes.Error, spans[1].Status.Code)
+	})
+}
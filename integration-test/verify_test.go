@@ -0,0 +1,74 @@
+package gormigrate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+var errNoPeople = errors.New("no people")
+
+func verifiedMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "201608301400",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&Person{}); err != nil {
+				return err
+			}
+			return tx.Create(&Person{Name: "John"}).Error
+		},
+		Verify: func(tx *gorm.DB) error {
+			var count int64
+			if err := tx.Model(&Person{}).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return errNoPeople
+			}
+			return nil
+		},
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dialects.forEachDB(t, func(db *gorm.DB) {
+		m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{verifiedMigration()})
+
+		require.NoError(t, m.Migrate())
+		report := m.Report()
+		require.Len(t, report.Migrations, 2)
+		assert.Equal(t, gormigrate.StepMigrate, report.Migrations[0].Step)
+		assert.Equal(t, gormigrate.StepVerify, report.Migrations[1].Step)
+		assert.Equal(t, "201608301400", report.Migrations[1].ID)
+		assert.Equal(t, int64(1), tableCount(t, db, "migrations"))
+	})
+}
+
+func TestVerifyFailure(t *testing.T) {
+	options := *gormigrate.DefaultOptions
+	options.UseTransaction = true
+
+	dialects.withTransactionSupport().forEachDB(t, func(db *gorm.DB) {
+		migration := verifiedMigration()
+		migration.Migrate = func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&Person{})
+		}
+		m := gormigrate.New(db, &options, []*gormigrate.Migration{migration})
+
+		err := m.Migrate()
+		var verificationErr *gormigrate.VerificationFailedError
+		require.ErrorAs(t, err, &verificationErr)
+		assert.Equal(t, "201608301400", verificationErr.ID)
+		assert.ErrorIs(t, err, errNoPeople)
+		assert.Equal(t, errNoPeople, m.Report().Migrations[1].Err)
+
+		// The migration has been rolled back with the transaction
+		assert.False(t, db.Migrator().HasTable(&Person{}))
+		assert.False(t, db.Migrator().HasTable("migrations"))
+	})
+}
@@ -0,0 +1,173 @@
+package gormigrate
+
+import (
+	"gorm.io/gorm"
+)
+
+// tableLayoutVersion is the version of the layout of the migration table
+// created by this version of gormigrate. Every change of the layout
+// increments it and adds an upgrade to layoutUpgrades.
+const tableLayoutVersion = 3
+
+// layoutUpgrades upgrade the migration table from the layout version of
+// their index plus one to the next version.
+var layoutUpgrades = []func(s *TableStore, tx *gorm.DB) error{
+	// 1 to 2: checksums of repeatable migrations
+	func(s *TableStore, tx *gorm.DB) error {
+		return tx.Table(s.table()).Migrator().AddColumn(s.model(), "Checksum")
+	},
+	// 2 to 3: status of migrations skipped by their precondition
+	func(s *TableStore, tx *gorm.DB) error {
+		return tx.Table(s.table()).Migrator().AddColumn(s.model(), "Status")
+	},
+}
+
+// tableLayout is the row of the layout table of a TableStore, which records
+// how its migration table was created.
+type tableLayout struct {
+	Version      int
+	IDColumnName string `gorm:"size:255"`
+	IDColumnSize int
+}
+
+// layoutTable returns the qualified name of the layout table, which is named
+// after the migration table.
+func (s *TableStore) layoutTable() string {
+	return s.table() + "_layout"
+}
+
+// Ensure implements Store. Migration tables created by older versions, or
+// with another IDColumnName or IDColumnSize, are upgraded to the current
+// layout: columns are added, and the ID column is renamed or resized.
+func (s *TableStore) Ensure(tx *gorm.DB) error {
+	exists, err := s.Exists(tx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := s.createSchema(tx); err != nil {
+			return err
+		}
+		if err := tx.Table(s.table()).Migrator().CreateTable(s.model()); err != nil {
+			return err
+		}
+		return s.saveLayout(tx)
+	}
+
+	layout, err := s.layout(tx)
+	if err != nil {
+		return err
+	}
+	if layout.Version == tableLayoutVersion && layout.IDColumnName == s.IDColumnName && layout.IDColumnSize == s.IDColumnSize {
+		return nil
+	}
+	for version := layout.Version; version < tableLayoutVersion; version++ {
+		if err := layoutUpgrades[version-1](s, tx); err != nil {
+			return err
+		}
+	}
+	migrator := tx.Table(s.table()).Migrator()
+	if layout.IDColumnName != s.IDColumnName {
+		if err := migrator.RenameColumn(s.model(), layout.IDColumnName, s.IDColumnName); err != nil {
+			return err
+		}
+	}
+	if layout.IDColumnSize != s.IDColumnSize {
+		if err := migrator.AlterColumn(s.model(), "ID"); err != nil {
+			return err
+		}
+	}
+	return s.saveLayout(tx)
+}
+
+// layout returns the layout of the existing migration table. The layout of
+// tables created before layouts were recorded is inferred from their columns,
+// the ID column being their primary key.
+func (s *TableStore) layout(tx *gorm.DB) (*tableLayout, error) {
+	tableSchema, table := s.names()
+	exists, err := hasTable(tx, tableSchema, table+"_layout")
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		var layouts []*tableLayout
+		if err := tx.Table(s.layoutTable()).Find(&layouts).Error; err != nil {
+			return nil, err
+		}
+		if len(layouts) > 0 {
+			return layouts[0], nil
+		}
+	}
+
+	idColumnName, idColumnSize, err := s.idColumn(tx)
+	if err != nil {
+		return nil, err
+	}
+	layout := &tableLayout{Version: 1, IDColumnName: idColumnName, IDColumnSize: idColumnSize}
+	hasChecksum, err := s.hasColumn(tx, "checksum")
+	if err != nil {
+		return nil, err
+	}
+	if hasChecksum {
+		layout.Version = 2
+	}
+	hasStatus, err := s.hasColumn(tx, "status")
+	if err != nil {
+		return nil, err
+	}
+	if hasStatus {
+		layout.Version = 3
+	}
+	return layout, nil
+}
+
+// idColumn returns the name and size of the primary key of the existing
+// migration table. The size defaults to IDColumnSize when the dialect does not
+// report it, and the column to IDColumnName when the table has no primary key.
+func (s *TableStore) idColumn(tx *gorm.DB) (string, int, error) {
+	tableSchema, table := s.names()
+	if tableSchema != "" && tx.Dialector.Name() == "sqlite" {
+		// The sqlite migrator does not support attached databases, which
+		// ignore the size of text columns anyway
+		var names []string
+		err := tx.Raw("SELECT name FROM pragma_table_info(?, ?) WHERE pk > 0", table, tableSchema).Scan(&names).Error
+		if err != nil || len(names) != 1 {
+			return s.IDColumnName, s.IDColumnSize, err
+		}
+		return names[0], s.IDColumnSize, nil
+	}
+
+	columnTypes, err := tx.Migrator().ColumnTypes(s.table())
+	if err != nil {
+		return "", 0, err
+	}
+	for _, columnType := range columnTypes {
+		if primaryKey, ok := columnType.PrimaryKey(); !ok || !primaryKey {
+			continue
+		}
+		size := s.IDColumnSize
+		if length, ok := columnType.Length(); ok && length > 0 {
+			size = int(length)
+		}
+		return columnType.Name(), size, nil
+	}
+	return s.IDColumnName, s.IDColumnSize, nil
+}
+
+// saveLayout records the current layout in the layout table, creating it if needed.
+func (s *TableStore) saveLayout(tx *gorm.DB) error {
+	tableSchema, table := s.names()
+	exists, err := hasTable(tx, tableSchema, table+"_layout")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := tx.Table(s.layoutTable()).Migrator().CreateTable(&tableLayout{}); err != nil {
+			return err
+		}
+	} else if err := tx.Table(s.layoutTable()).Where("1 = 1").Delete(&tableLayout{}).Error; err != nil {
+		return err
+	}
+	layout := &tableLayout{Version: tableLayoutVersion, IDColumnName: s.IDColumnName, IDColumnSize: s.IDColumnSize}
+	return insertInto(tx, s.layoutTable(), layout)
+}
@@ -0,0 +1,112 @@
+package gormigrate
+
+import (
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// MemoryStore is a Store keeping the applied migrations in memory, e.g. for
+// unit tests. The zero value is an empty store.
+type MemoryStore struct {
+	mu         sync.Mutex
+	running    sync.Mutex
+	exists     bool
+	migrations []*AppliedMigration
+}
+
+// Exists implements Store.
+func (s *MemoryStore) Exists(*gorm.DB) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.exists, nil
+}
+
+// Ensure implements Store.
+func (s *MemoryStore) Ensure(*gorm.DB) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exists = true
+	return nil
+}
+
+// List implements Store.
+func (s *MemoryStore) List(*gorm.DB) ([]*AppliedMigration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyApplied(s.migrations), nil
+}
+
+// Find implements Store.
+func (s *MemoryStore) Find(_ *gorm.DB, id string) (*AppliedMigration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return findApplied(s.migrations, id), nil
+}
+
+// Record implements Store.
+func (s *MemoryStore) Record(_ *gorm.DB, m *AppliedMigration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.migrations = recordApplied(s.migrations, m)
+	return nil
+}
+
+// Remove implements Store.
+func (s *MemoryStore) Remove(_ *gorm.DB, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.migrations = removeApplied(s.migrations, id)
+	return nil
+}
+
+// Lock implements Store. It only prevents concurrent runs within the process.
+func (s *MemoryStore) Lock(*gorm.DB) (func() error, error) {
+	s.running.Lock()
+	return func() error {
+		s.running.Unlock()
+		return nil
+	}, nil
+}
+
+func copyApplied(migrations []*AppliedMigration) []*AppliedMigration {
+	copied := make([]*AppliedMigration, len(migrations))
+	for i, m := range migrations {
+		c := *m
+		copied[i] = &c
+	}
+	return copied
+}
+
+func findApplied(migrations []*AppliedMigration, id string) *AppliedMigration {
+	for _, m := range migrations {
+		if m.ID == id {
+			c := *m
+			return &c
+		}
+	}
+	return nil
+}
+
+// recordApplied returns migrations with m added, or replacing the migration of the same ID.
+func recordApplied(migrations []*AppliedMigration, m *AppliedMigration) []*AppliedMigration {
+	c := *m
+	for i, existing := range migrations {
+		if existing.ID == m.ID {
+			migrations[i] = &c
+			return migrations
+		}
+	}
+	return append(migrations, &c)
+}
+
+// removeApplied returns migrations without the migration of the given ID.
+func removeApplied(migrations []*AppliedMigration, id string) []*AppliedMigration {
+	kept := migrations[:0]
+	for _, m := range migrations {
+		if m.ID != id {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,64 @@
+package gormigrate
+
+import (
+	"context"
+	"time"
+)
+
+// Metrics records the outcome and duration of migrations, and the state of
+// the database. See the gormigrateprom package for a Prometheus implementation.
+type Metrics interface {
+	// ObserveMigration records a step of a migration that ran, with the
+	// error it returned. Steps are observed as they run, even when the
+	// transaction of the run is rolled back afterwards.
+	ObserveMigration(id string, step Step, duration time.Duration, err error)
+	// SetPending records the number of migrations that did not run yet, see
+	// StatePending and StatePreconditionFailed.
+	SetPending(count int)
+	// SetHead records the ID of the last migration recorded as ran that is
+	// not repeatable, including the ones skipped by their precondition, or ""
+	// if none ran.
+	SetHead(id string)
+}
+
+// observeMigration records a step that ran in Options.Metrics.
+func (g *Gormigrate) observeMigration(report *MigrationReport) {
+	if g.options.Metrics != nil {
+		g.options.Metrics.ObserveMigration(report.ID, report.Step, report.Duration, report.Err)
+	}
+}
+
+// observeState records the pending migrations and the head in
+// Options.Metrics, at the end of a run. They are read from the store only,
+// without evaluating the preconditions of the migrations again.
+func (g *Gormigrate) observeState() {
+	if g.options.Metrics == nil {
+		return
+	}
+	statuses, err := g.recordedStatuses()
+	if err != nil {
+		g.db.Logger.Error(context.TODO(), "gormigrate: cannot observe the migration state: %v", err)
+		return
+	}
+	g.observeStatuses(statuses)
+}
+
+// observeStatuses records the pending migrations and the head of the
+// statuses returned by Status in Options.Metrics.
+func (g *Gormigrate) observeStatuses(statuses []*MigrationStatus) {
+	if g.options.Metrics == nil {
+		return
+	}
+	pending := 0
+	head := ""
+	for i, status := range statuses {
+		switch {
+		case status.State.pending():
+			pending++
+		case (status.State == StateApplied || status.State == StateSkippedByPrecondition) && !g.migrations[i].Repeatable:
+			head = status.ID
+		}
+	}
+	g.options.Metrics.SetPending(pending)
+	g.options.Metrics.SetHead(head)
+}
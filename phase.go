@@ -0,0 +1,72 @@
+package gormigrate
+
+// Phase is the deploy phase a migration belongs to.
+type Phase string
+
+const (
+	// PhasePreDeploy migrations run before the new code is rolled out,
+	// e.g. additive changes like new tables and columns.
+	PhasePreDeploy Phase = "pre-deploy"
+	// PhasePostDeploy migrations run after the new code is rolled out,
+	// e.g. destructive cleanups like dropping unused columns.
+	PhasePostDeploy Phase = "post-deploy"
+)
+
+// MigratePhase executes the migrations of the given phase that did not run yet.
+// A post-deploy migration never runs before the pre-deploy migrations defined before it.
+func (g *Gormigrate) MigratePhase(phase Phase) error {
+	if phase == "" {
+		return ErrMissingPhase
+	}
+	if !g.hasMigrations() {
+		return ErrNoMigrationDefined
+	}
+	return g.migrate(g.lastVersionedID(), phase)
+}
+
+// PendingByPhase returns the IDs of the pending migrations of each phase,
+// as reported by Status.
+func (g *Gormigrate) PendingByPhase() (map[Phase][]string, error) {
+	statuses, err := g.Status()
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[Phase][]string)
+	for _, status := range statuses {
+		if status.State.pending() {
+			pending[status.Phase] = append(pending[status.Phase], status.ID)
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migration) phase() Phase {
+	if m.Phase == "" {
+		return PhasePreDeploy
+	}
+	return m.Phase
+}
+
+// checkPhaseOrder returns a *PhaseOrderError if m is a post-deploy migration
+// and a pre-deploy migration defined before it did not run yet.
+func (g *Gormigrate) checkPhaseOrder(m *Migration) error {
+	if m.phase() != PhasePostDeploy {
+		return nil
+	}
+	for _, migration := range g.migrations {
+		if migration == m {
+			return nil
+		}
+		if migration.Repeatable || !g.selected(migration) || migration.phase() != PhasePreDeploy {
+			continue
+		}
+		migrationRan, err := g.migrationRan(migration)
+		if err != nil {
+			return err
+		}
+		if !migrationRan {
+			return &PhaseOrderError{ID: m.ID, PendingID: migration.ID}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package gormigrate
+
+// checkPrecondition evaluates the precondition of the migration and returns
+// whether the migration should run. When the migration is skipped with
+// PreconditionSkipAndMarkRan, markRan is called to record it as skipped.
+func (g *Gormigrate) checkPrecondition(m *Migration, markRan func(skipped bool) error) (bool, error) {
+	if m.Precondition == nil {
+		return true, nil
+	}
+	ok, err := m.Precondition(g.tx)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return true, nil
+	}
+
+	switch m.OnPreconditionFail {
+	case PreconditionSkipAndMarkRan:
+		g.tx.Logger.Warn(g.tx.Statement.Context, "gormigrate: precondition failed, migration %q skipped and marked as ran", m.ID)
+		g.addReport(&MigrationReport{ID: m.ID, Skipped: true})
+		return false, markRan(true)
+	case PreconditionSkip:
+		g.tx.Logger.Warn(g.tx.Statement.Context, "gormigrate: precondition failed, migration %q skipped", m.ID)
+		g.addReport(&MigrationReport{ID: m.ID, Skipped: true})
+		return false, nil
+	default:
+		return false, &PreconditionFailedError{ID: m.ID}
+	}
+}
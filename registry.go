@@ -0,0 +1,95 @@
+package gormigrate
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Registry collects migrations defined in separate files, each registering
+// its migration from an init func, like the files created by Scaffold.
+type Registry struct {
+	// Format is the format of the IDs, used to order the migrations.
+	// IDs are compared as strings when empty.
+	Format IDFormat
+
+	migrations []*Migration
+}
+
+// Register adds migrations to the registry.
+func (r *Registry) Register(migrations ...*Migration) {
+	r.migrations = append(r.migrations, migrations...)
+}
+
+// Migrations returns the registered migrations, ordered by ID.
+func (r *Registry) Migrations() []*Migration {
+	migrations := make([]*Migration, len(r.migrations))
+	copy(migrations, r.migrations)
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return r.Format.less(migrations[i].ID, migrations[j].ID)
+	})
+	return migrations
+}
+
+const (
+	sqlUpSuffix   = ".up.sql"
+	sqlDownSuffix = ".down.sql"
+)
+
+// SQLMigrations returns the migrations defined by the SQL files at the root of
+// fsys, usually an embed.FS. A "<id>_<name>.up.sql" file holds the statements
+// run by Migrate, and an optional "<id>_<name>.down.sql" file the statements
+// run by Rollback.
+//
+// Each file is executed at once, so the driver must accept several statements
+// in a single query, which needs multiStatements=true in MySQL DSNs.
+func SQLMigrations(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []*Migration
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, sqlUpSuffix) {
+			continue
+		}
+		up, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		migration := &Migration{
+			ID:      sqlMigrationID(name),
+			Migrate: execSQL(string(up)),
+		}
+
+		down, err := fs.ReadFile(fsys, strings.TrimSuffix(name, sqlUpSuffix)+sqlDownSuffix)
+		switch {
+		case err == nil:
+			migration.Rollback = execSQL(string(down))
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+// sqlMigrationID returns the ID of the migration of the SQL file with the given name.
+func sqlMigrationID(name string) string {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, sqlUpSuffix), sqlDownSuffix)
+	if i := strings.Index(name, "_"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+func execSQL(sql string) func(*gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Exec(sql).Error
+	}
+}
@@ -0,0 +1,95 @@
+package gormigrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Checksum returns a checksum of the given content, to be used as the Checksum
+// of a repeatable migration. Pass everything the migration applies,
+// e.g. the SQL defining a view.
+func Checksum(content ...string) string {
+	h := sha256.New()
+	for _, c := range content {
+		h.Write([]byte(c))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lastVersionedID returns the ID of the last migration that is not repeatable,
+// or "" if there is none.
+func (g *Gormigrate) lastVersionedID() string {
+	for i := len(g.migrations) - 1; i >= 0; i-- {
+		if !g.migrations[i].Repeatable {
+			return g.migrations[i].ID
+		}
+	}
+	return ""
+}
+
+// runRepeatableMigrations runs the repeatable migrations that changed.
+// When phase is not empty, only the migrations of this phase run.
+func (g *Gormigrate) runRepeatableMigrations(phase Phase) error {
+	for _, migration := range g.migrations {
+		if !migration.Repeatable || !g.selected(migration) || (phase != "" && migration.phase() != phase) {
+			continue
+		}
+		if err := g.runRepeatableMigration(migration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Gormigrate) runRepeatableMigration(migration *Migration) error {
+	if len(migration.ID) == 0 {
+		return ErrMissingID
+	}
+	if len(migration.Checksum) == 0 {
+		return ErrMissingChecksum
+	}
+
+	checksum, migrationRan, err := g.lastChecksum(migration)
+	if err != nil {
+		return err
+	}
+	if migrationRan && checksum == migration.Checksum {
+		return nil
+	}
+
+	if err := g.checkPhaseOrder(migration); err != nil {
+		return err
+	}
+	shouldRun, err := g.checkPrecondition(migration, func(skipped bool) error {
+		return g.saveChecksum(migration, migrationRan, skipped)
+	})
+	if err != nil || !shouldRun {
+		return err
+	}
+	if err := g.runMigrate(migration); err != nil {
+		return err
+	}
+	return g.saveChecksum(migration, migrationRan, false)
+}
+
+// lastChecksum returns the checksum recorded when the migration last ran,
+// and whether it ran at all.
+func (g *Gormigrate) lastChecksum(m *Migration) (string, bool, error) {
+	applied, err := g.stateStore().Find(g.state, m.ID)
+	if err != nil || applied == nil {
+		return "", false, err
+	}
+	return applied.Checksum, true, nil
+}
+
+// saveChecksum records the checksum of a repeatable migration that just ran,
+// or was skipped by its precondition, replacing the record of its previous run.
+func (g *Gormigrate) saveChecksum(m *Migration, migrationRan, skipped bool) error {
+	if migrationRan {
+		if err := g.deleteMigration(m.ID); err != nil {
+			return err
+		}
+	}
+	return g.insertMigration(&AppliedMigration{ID: m.ID, Checksum: m.Checksum, Skipped: skipped})
+}
@@ -0,0 +1,93 @@
+package gormigrate
+
+import (
+	"io"
+	"time"
+)
+
+// Step is a stage in the life of a migration.
+type Step string
+
+const (
+	// StepMigrate is the run of the Migrate function of a migration.
+	StepMigrate Step = "migrate"
+	// StepRollback is the run of the Rollback function of a migration.
+	StepRollback Step = "rollback"
+	// StepInitSchema is the run of the InitSchema function.
+	StepInitSchema Step = "init schema"
+	// StepVerify is the run of the Verify function of a migration.
+	StepVerify Step = "verify"
+)
+
+// Report describes the migrations run by the last call to Migrate, MigrateTo
+// or one of the Rollback methods.
+type Report struct {
+	// Migrations lists the migrations in the order they were run.
+	Migrations []*MigrationReport
+}
+
+// MigrationReport describes a single step of a migration.
+type MigrationReport struct {
+	// ID is the migration identifier.
+	ID string
+	// Step is the step of the migration that ran.
+	Step Step
+	// Skipped is true when the migration did not run because its precondition failed.
+	Skipped bool
+	// Duration is the time spent running the function of the step.
+	Duration time.Duration
+	// Err is the error returned by the function of the step, if any.
+	Err error
+	// Statements are the SQL statements executed by the function of the step.
+	// They are only captured when Options.CaptureStatements is set.
+	Statements []*Statement
+}
+
+// Statement is an SQL statement executed by a migration.
+type Statement struct {
+	// SQL is the statement with its arguments inlined.
+	SQL string
+	// Duration is the time the statement took to execute.
+	Duration time.Duration
+	// RowsAffected is the number of rows affected by the statement.
+	RowsAffected int64
+	// Err is the error returned by the database, if any.
+	Err error
+}
+
+// Report returns the report of the last run, or nil if nothing ran yet.
+func (g *Gormigrate) Report() *Report {
+	return g.report
+}
+
+func (g *Gormigrate) addReport(report *MigrationReport) {
+	if g.report != nil {
+		g.report.Migrations = append(g.report.Migrations, report)
+	}
+}
+
+// WriteSQL writes the statements captured for each migration of the report to w,
+// as an SQL script that can be replayed against another database.
+func (r *Report) WriteSQL(w io.Writer) error {
+	var lines []string
+	for _, migration := range r.Migrations {
+		lines = append(lines, migrationComment(migration.ID, migration.Step))
+		for _, statement := range migration.Statements {
+			lines = append(lines, statement.SQL)
+		}
+	}
+	return writeSQL(w, lines)
+}
+
+func migrationComment(id string, step Step) string {
+	switch step {
+	case StepRollback:
+		return "-- Rollback: " + id
+	case StepInitSchema:
+		return "-- Init schema: " + id
+	case StepVerify:
+		return "-- Verify: " + id
+	default:
+		return "-- Migration: " + id
+	}
+}
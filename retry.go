@@ -0,0 +1,249 @@
+package gormigrate
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RetryPolicy retries the runs failing with a transient error, like a
+// deadlock with the application traffic.
+//
+// A retried run starts over from a new transaction, so retrying requires
+// Options.UseTransaction: all the migrations of the failed attempt are rolled
+// back and run again. On dialects committing DDL implicitly, like MySQL, the
+// statements a migration ran before failing are not rolled back, so such
+// migrations should be safe to re-run.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a migration is attempted, including
+	// the first one. Nothing is retried when lower than 2.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry. It doubles with every
+	// following retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries. The wait is not capped when zero.
+	MaxBackoff time.Duration
+	// IsTransient reports whether an error of the dialect is worth retrying.
+	// Defaults to IsTransientError.
+	IsTransient func(dialect string, err error) bool
+}
+
+// DefaultRetryPolicy attempts migrations three times, waiting up to a few
+// seconds between attempts.
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+}
+
+// backoff returns the wait before the given retry, starting at 1.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < retry; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+func (p *RetryPolicy) isTransient(dialect string, err error) bool {
+	if p.IsTransient != nil {
+		return p.IsTransient(dialect, err)
+	}
+	return IsTransientError(dialect, err)
+}
+
+// transientSQLStates are the Postgres SQLSTATE codes of transient errors:
+// serialization failures, deadlocks, lock timeouts and lost connections.
+var transientSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"55P03": true, // lock_not_available
+	"57P01": true, // admin_shutdown
+	"08000": true, // connection_exception
+	"08003": true, // connection_does_not_exist
+	"08006": true, // connection_failure
+}
+
+// transientErrorNumbers are the error numbers of transient errors for the
+// dialects reporting numbers instead of SQLSTATE codes.
+var transientErrorNumbers = map[string]map[int64]bool{
+	"mysql": {
+		1053: true, // ER_SERVER_SHUTDOWN
+		1205: true, // ER_LOCK_WAIT_TIMEOUT
+		1213: true, // ER_LOCK_DEADLOCK
+	},
+	"sqlserver": {
+		1205:  true, // deadlock victim
+		1222:  true, // lock request time out
+		10053: true, // transport-level error, connection aborted
+		10054: true, // transport-level error, connection reset
+		10060: true, // transport-level error, connection timed out
+		40197: true, // Azure SQL service error, e.g. failover
+		40501: true, // Azure SQL service busy
+		40613: true, // Azure SQL database unavailable
+	},
+	"sqlite": {
+		5: true, // SQLITE_BUSY
+		6: true, // SQLITE_LOCKED
+	},
+}
+
+// connectionErrorPrefixes start the messages of the errors reporting a lost
+// connection, for the drivers whose errors are recognized by nothing else:
+// ErrInvalidConn of go-sql-driver/mysql and StreamError of go-mssqldb.
+var connectionErrorPrefixes = map[string]string{
+	"mysql":     "invalid connection",
+	"sqlserver": "Invalid TDS stream",
+}
+
+// IsTransientError reports whether err, returned by a database of the given
+// dialect, is transient: a deadlock, a serialization failure, a lock wait
+// timeout or a lost connection.
+//
+// The drivers are not imported: Postgres errors are recognized by their
+// SQLState method, as with pgx and lib/pq, and the errors of the other
+// dialects by their error number, as with the Number field of the MySQL and
+// SQL Server drivers or the Code of the SQLite ones.
+func IsTransientError(dialect string, err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.As(err, &netErr) {
+		return true
+	}
+	if dialect == "sqlserver" && errors.Is(err, io.EOF) {
+		// go-mssqldb returns the io.EOF of the connection closed by the server
+		return true
+	}
+	if prefix, ok := connectionErrorPrefixes[dialect]; ok {
+		for e := err; e != nil; e = errors.Unwrap(e) {
+			if strings.HasPrefix(e.Error(), prefix) {
+				return true
+			}
+		}
+	}
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) && transientSQLStates[state.SQLState()] {
+		return true
+	}
+	numbers, ok := transientErrorNumbers[dialect]
+	if !ok {
+		return false
+	}
+	number, ok := errorNumber(err)
+	if dialect == "sqlite" {
+		// Extended result codes keep the primary code in their low byte
+		number &= 0xff
+	}
+	return ok && numbers[number]
+}
+
+// errorNumber returns the number of the first error of the chain having one,
+// through a SQLErrorNumber or Code method, or a Number or Code field.
+func errorNumber(err error) (int64, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch e := err.(type) {
+		case interface{ SQLErrorNumber() int32 }:
+			return int64(e.SQLErrorNumber()), true
+		case interface{ Code() int }:
+			return int64(e.Code()), true
+		}
+		v := reflect.ValueOf(err)
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		for _, name := range []string{"Number", "Code"} {
+			field := v.FieldByName(name)
+			switch field.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				return field.Int(), true
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				return int64(field.Uint()), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// checkRetry refuses retry policies without Options.UseTransaction.
+func (g *Gormigrate) checkRetry() error {
+	if g.options.UseTransaction {
+		return nil
+	}
+	retried := g.options.Retry != nil && g.options.Retry.MaxAttempts > 1
+	for _, m := range g.migrations {
+		retried = retried || m.Retry != nil && m.Retry.MaxAttempts > 1
+	}
+	if retried {
+		return ErrRetryWithoutTransaction
+	}
+	return nil
+}
+
+// retry calls fn, the attempt of a run, until it succeeds or fails with an
+// error that is not retried by the policy of the failed migration.
+func (g *Gormigrate) retry(fn func() error) error {
+	failures := make(map[string]int)
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		id, policy := g.failedRetryPolicy()
+		failures[id]++
+		if policy == nil || failures[id] >= policy.MaxAttempts || !policy.isTransient(g.db.Dialector.Name(), err) {
+			return err
+		}
+
+		ctx := contextOf(g.db)
+		backoff := policy.backoff(failures[id])
+		g.db.Logger.Warn(ctx, "gormigrate: Retrying migration %q in %s after a transient error: %v", id, backoff, err)
+		trace.SpanFromContext(ctx).AddEvent("gormigrate.retry", trace.WithAttributes(
+			MigrationIDKey.String(id),
+			attribute.Int("gormigrate.attempt", failures[id]+1),
+		))
+		if err := sleep(ctx, backoff); err != nil {
+			return err
+		}
+	}
+}
+
+// failedRetryPolicy returns the ID and the retry policy of the migration that
+// failed the last attempt. Errors outside of migrations, like the ones of the
+// migration table, use Options.Retry.
+func (g *Gormigrate) failedRetryPolicy() (string, *RetryPolicy) {
+	id := ""
+	if g.report != nil {
+		for i := len(g.report.Migrations) - 1; i >= 0; i-- {
+			if g.report.Migrations[i].Err != nil {
+				id = g.report.Migrations[i].ID
+				break
+			}
+		}
+	}
+	for _, m := range g.migrations {
+		if m.ID == id && m.Retry != nil {
+			return id, m.Retry
+		}
+	}
+	return id, g.options.Retry
+}
@@ -0,0 +1,238 @@
+package gormigrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultScaffoldRegistry = "Migrations"
+	registryFileName        = "registry.go"
+	sqlRegistryFileName     = "registry_sql.go"
+)
+
+// ScaffoldOptions configures Scaffold.
+type ScaffoldOptions struct {
+	// Dir is the directory of the migrations package.
+	Dir string
+	// Package is the package name. Defaults to the base name of Dir.
+	Package string
+	// Name describes the migration, e.g. "add_user_age". It is part of the file names.
+	Name string
+	// ID is the ID of the migration. Defaults to the next ID after the ones of
+	// the files of Dir, see IDFormat.Next.
+	ID string
+	// IDFormat is the format of the IDs. Defaults to IDFormatTimestamp.
+	IDFormat IDFormat
+	// IDColumnSize is the maximum length of IDs, see Options.IDColumnSize. Defaults to 255.
+	IDColumnSize int
+	// SQL creates SQL files, see SQLMigrations, instead of a Go file.
+	SQL bool
+	// Registry is the name of the package variable holding the *Registry the
+	// migration is added to. Defaults to "Migrations".
+	Registry string
+}
+
+// Scaffold creates the files of a new migration in options.Dir: a Go file
+// registering a migration with stub Migrate and Rollback functions, or up and
+// down SQL files. The package variable holding the *Registry is declared in a
+// registry.go file created with the first migration, and the SQL files are
+// registered by a registry_sql.go file created with the first SQL migration.
+// Scaffold returns the paths of the created files.
+//
+// The ID must be in options.IDFormat, not longer than options.IDColumnSize
+// and not used by another file of options.Dir.
+func Scaffold(options *ScaffoldOptions) ([]string, error) {
+	if options.Dir == "" || options.Name == "" {
+		return nil, ErrInvalidScaffoldOptions
+	}
+	pkg := options.Package
+	if pkg == "" {
+		pkg = filepath.Base(options.Dir)
+	}
+	format := options.IDFormat
+	if format == "" {
+		format = IDFormatTimestamp
+	}
+	size := options.IDColumnSize
+	if size == 0 {
+		size = DefaultOptions.IDColumnSize
+	}
+	registry := options.Registry
+	if registry == "" {
+		registry = defaultScaffoldRegistry
+	}
+
+	if err := os.MkdirAll(options.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	ids, err := scaffoldedIDs(options.Dir, format)
+	if err != nil {
+		return nil, err
+	}
+	id := options.ID
+	if id == "" {
+		if id, err = format.Next(ids, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if err := format.Validate(id); err != nil {
+		return nil, err
+	}
+	if len(id) > size {
+		return nil, &IDTooLongError{ID: id, Size: size}
+	}
+	for _, existing := range ids {
+		if existing == id {
+			return nil, &DuplicatedIDError{ID: id}
+		}
+	}
+
+	files := make(map[string]string)
+	registryPath := filepath.Join(options.Dir, registryFileName)
+	sqlRegistryPath := filepath.Join(options.Dir, sqlRegistryFileName)
+	if err := addMissingFile(files, registryPath, registrySource(pkg, registry, format)); err != nil {
+		return nil, err
+	}
+	if options.SQL {
+		if err := addMissingFile(files, sqlRegistryPath, sqlRegistrySource(pkg, registry)); err != nil {
+			return nil, err
+		}
+	}
+	base := filepath.Join(options.Dir, id+"_"+options.Name)
+	if options.SQL {
+		files[base+sqlUpSuffix] = fmt.Sprintf("-- Statements of migration %s\n", id)
+		files[base+sqlDownSuffix] = fmt.Sprintf("-- Statements undoing migration %s\n", id)
+	} else {
+		files[base+".go"] = migrationSource(pkg, registry, id)
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, path := range []string{registryPath, sqlRegistryPath, base + ".go", base + sqlUpSuffix, base + sqlDownSuffix} {
+		content, ok := files[path]
+		if !ok {
+			continue
+		}
+		if err := writeNewFile(path, content); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// scaffoldedIDs returns the IDs of the migration files of dir, which are the
+// ones whose name starts with an ID in the format.
+func scaffoldedIDs(dir string, format IDFormat) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".go") || strings.HasSuffix(name, ".sql")) {
+			continue
+		}
+		id := sqlMigrationID(strings.TrimSuffix(name, ".go"))
+		if seen[id] || format.Validate(id) != nil {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+var idFormatConstants = map[IDFormat]string{
+	IDFormatTimestamp: "IDFormatTimestamp",
+	IDFormatSequence:  "IDFormatSequence",
+	IDFormatSemver:    "IDFormatSemver",
+}
+
+// addMissingFile adds the file to files when it does not exist yet.
+func addMissingFile(files map[string]string, path, content string) error {
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		files[path] = content
+		return nil
+	}
+	return err
+}
+
+func registrySource(pkg, registry string, format IDFormat) string {
+	return fmt.Sprintf(`package %s
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+// %s holds the migrations of the package, registered by the init funcs of
+// the migration files.
+var %s = &gormigrate.Registry{Format: gormigrate.%s}
+`, pkg, registry, registry, idFormatConstants[format])
+}
+
+// sqlRegistrySource registers the SQL files of the package. It is only
+// created with the first SQL migration, since go:embed patterns must match.
+func sqlRegistrySource(pkg, registry string) string {
+	return fmt.Sprintf(`package %s
+
+import (
+	"embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+//go:embed *.sql
+var sqlFiles embed.FS
+
+func init() {
+	migrations, err := gormigrate.SQLMigrations(sqlFiles)
+	if err != nil {
+		panic(err)
+	}
+	%s.Register(migrations...)
+}
+`, pkg, registry)
+}
+
+func migrationSource(pkg, registry, id string) string {
+	return fmt.Sprintf(`package %s
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func init() {
+	%s.Register(&gormigrate.Migration{
+		ID: %q,
+		Migrate: func(tx *gorm.DB) error {
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`, pkg, registry, id)
+}
+
+// writeNewFile writes content to a file that must not exist yet.
+func writeNewFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
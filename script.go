@@ -0,0 +1,344 @@
+package gormigrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrScriptUnsupportedQuery is returned when a migration run by Script or
+// RollbackScript needs the result of a statement that would write to the database.
+var ErrScriptUnsupportedQuery = errors.New("gormigrate: Statement returning rows cannot be scripted")
+
+// scriptRecorder is a gorm.ConnPool that records every statement sent through
+// ExecContext instead of executing it. Read queries are passed through to the
+// wrapped pool, so migrations can still inspect the current schema, while
+// other queries fail with ErrScriptUnsupportedQuery.
+type scriptRecorder struct {
+	gorm.ConnPool
+	dialector gorm.Dialector
+	lines     []string
+}
+
+func (r *scriptRecorder) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	r.lines = append(r.lines, r.dialector.Explain(query, args...))
+	return driver.RowsAffected(0), nil
+}
+
+func (r *scriptRecorder) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if !isReadQuery(query) {
+		return nil, ErrScriptUnsupportedQuery
+	}
+	return r.ConnPool.QueryContext(ctx, query, args...)
+}
+
+func (r *scriptRecorder) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if !isReadQuery(query) {
+		return unsupportedQueries.QueryRowContext(ctx, query)
+	}
+	return r.ConnPool.QueryRowContext(ctx, query, args...)
+}
+
+// unsupportedQueries is a database whose connections fail with
+// ErrScriptUnsupportedQuery, since a sql.Row holding an error can only be
+// returned by database/sql.
+var unsupportedQueries = sql.OpenDB(unsupportedConnector{})
+
+type unsupportedConnector struct{}
+
+func (unsupportedConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, ErrScriptUnsupportedQuery
+}
+
+func (c unsupportedConnector) Driver() driver.Driver {
+	return c
+}
+
+func (unsupportedConnector) Open(string) (driver.Conn, error) {
+	return nil, ErrScriptUnsupportedQuery
+}
+
+func (r *scriptRecorder) comment(comment string) {
+	r.lines = append(r.lines, comment)
+}
+
+func isReadQuery(query string) bool {
+	query = strings.ToUpper(strings.TrimLeft(query, " \t\r\n("))
+	for _, prefix := range []string{"SELECT", "SHOW", "PRAGMA", "EXPLAIN", "DESCRIBE"} {
+		if strings.HasPrefix(query, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptPlan holds what Migrate would do against the current database state.
+type scriptPlan struct {
+	hasTable   bool
+	initSchema bool
+	pending    []*Migration
+	// repeatable lists the repeatable migrations to run, mapped to
+	// whether they ran before.
+	repeatable    []*Migration
+	repeatableRan map[string]bool
+}
+
+// Script writes the SQL statements that Migrate would execute to w, without executing them.
+// The database is only read, to find out which migrations are pending. The script
+// includes the creation of the migration table and the statements that record
+// each migration as applied.
+//
+// Statements are captured as the migrations run against a connection that discards
+// writes, so a migration that reads back data or schema changed earlier in the same
+// script will not see those changes.
+// Verify functions are not run, as they would check changes that were not applied.
+// Scripts cannot be generated when the migration table is kept in a separate
+// database, see UseStateDB, or with a Store other than a TableStore.
+func (g *Gormigrate) Script(w io.Writer) error {
+	plan, err := g.planScript()
+	if err != nil {
+		return err
+	}
+
+	rec, err := g.record(func(rec *scriptRecorder) error {
+		if !plan.hasTable {
+			rec.comment("-- Create migration table")
+		}
+		if err := g.createMigrationTableIfNotExists(); err != nil {
+			return err
+		}
+		if plan.initSchema {
+			rec.comment(migrationComment(initSchemaMigrationID, StepInitSchema))
+			if err := g.initSchema(g.tx); err != nil {
+				return err
+			}
+			return g.insertInitSchemaMigrations()
+		}
+		for _, migration := range plan.pending {
+			rec.comment(migrationComment(migration.ID, StepMigrate))
+			markRan := func(skipped bool) error {
+				return g.insertMigration(&AppliedMigration{ID: migration.ID, Skipped: skipped})
+			}
+			if err := g.scriptMigration(migration, markRan); err != nil {
+				return err
+			}
+		}
+		for _, migration := range plan.repeatable {
+			rec.comment(migrationComment(migration.ID, StepMigrate))
+			markRan := func(skipped bool) error {
+				return g.saveChecksum(migration, plan.repeatableRan[migration.ID], skipped)
+			}
+			if err := g.scriptMigration(migration, markRan); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writeSQL(w, rec.lines)
+}
+
+// RollbackScript writes the SQL statements that undo the migrations Script would
+// apply to w, in reverse order, without executing them. Repeatable migrations
+// are not rolled back.
+// It returns ErrRollbackImpossible if Script would run the InitSchema function or
+// if any of the pending migrations has no rollback function.
+func (g *Gormigrate) RollbackScript(w io.Writer) error {
+	plan, err := g.planScript()
+	if err != nil {
+		return err
+	}
+	if plan.initSchema {
+		return ErrRollbackImpossible
+	}
+
+	rec, err := g.record(func(rec *scriptRecorder) error {
+		for i := len(plan.pending) - 1; i >= 0; i-- {
+			migration := plan.pending[i]
+			rec.comment(migrationComment(migration.ID, StepRollback))
+			rollback, err := migration.rollbackFunc(g.tx.Dialector.Name())
+			if err != nil {
+				return err
+			}
+			if err := rollback(g.tx); err != nil {
+				return err
+			}
+			if err := g.deleteMigration(migration.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writeSQL(w, rec.lines)
+}
+
+// scriptMigration runs the Migrate function of the migration followed by markRan,
+// unless its precondition fails.
+func (g *Gormigrate) scriptMigration(migration *Migration, markRan func(skipped bool) error) error {
+	if migration.Backfill != nil {
+		return ErrScriptBackfill
+	}
+	shouldRun, err := g.checkPrecondition(migration, markRan)
+	if err != nil || !shouldRun {
+		return err
+	}
+	migrate, err := migration.migrateFunc(g.tx.Dialector.Name())
+	if err != nil {
+		return err
+	}
+	if err := migrate(g.tx); err != nil {
+		return err
+	}
+	return markRan(false)
+}
+
+func (g *Gormigrate) planScript() (*scriptPlan, error) {
+	if !g.hasMigrations() {
+		return nil, ErrNoMigrationDefined
+	}
+	if g.stateDB != nil {
+		return nil, ErrScriptStateDB
+	}
+	if _, ok := g.stateStore().(*TableStore); !ok {
+		return nil, ErrScriptStore
+	}
+	if err := g.checkReservedID(); err != nil {
+		return nil, err
+	}
+	if err := g.checkDuplicatedID(); err != nil {
+		return nil, err
+	}
+	if err := g.checkIDs(); err != nil {
+		return nil, err
+	}
+	for _, migration := range g.migrations {
+		if len(migration.ID) == 0 {
+			return nil, ErrMissingID
+		}
+		if migration.Repeatable && len(migration.Checksum) == 0 {
+			return nil, ErrMissingChecksum
+		}
+	}
+
+	g.tx = g.db
+	g.state = g.db
+	hasTable, err := g.stateStore().Exists(g.state)
+	if err != nil {
+		return nil, err
+	}
+	plan := &scriptPlan{
+		hasTable:      hasTable,
+		repeatableRan: make(map[string]bool),
+	}
+
+	if !plan.hasTable {
+		plan.initSchema = g.initSchema != nil
+		for _, migration := range g.migrations {
+			if !g.selected(migration) {
+				continue
+			}
+			if migration.Repeatable {
+				plan.repeatable = append(plan.repeatable, migration)
+			} else {
+				plan.pending = append(plan.pending, migration)
+			}
+		}
+		return plan, nil
+	}
+
+	if g.options.ValidateUnknownMigrations {
+		unknownMigrations, err := g.unknownMigrationsHaveHappened()
+		if err != nil {
+			return nil, err
+		}
+		if unknownMigrations {
+			return nil, ErrUnknownPastMigration
+		}
+	}
+
+	if g.initSchema != nil {
+		canInitializeSchema, err := g.canInitializeSchema()
+		if err != nil {
+			return nil, err
+		}
+		if canInitializeSchema {
+			plan.initSchema = true
+			return plan, nil
+		}
+	}
+
+	for _, migration := range g.migrations {
+		if migration.Repeatable || !g.selected(migration) {
+			continue
+		}
+		migrationRan, err := g.migrationRan(migration)
+		if err != nil {
+			return nil, err
+		}
+		if !migrationRan {
+			plan.pending = append(plan.pending, migration)
+		}
+	}
+	for _, migration := range g.migrations {
+		if !migration.Repeatable || !g.selected(migration) {
+			continue
+		}
+		checksum, migrationRan, err := g.lastChecksum(migration)
+		if err != nil {
+			return nil, err
+		}
+		if !migrationRan || checksum != migration.Checksum {
+			plan.repeatable = append(plan.repeatable, migration)
+			plan.repeatableRan[migration.ID] = migrationRan
+		}
+	}
+	return plan, nil
+}
+
+// record runs fn with g.tx set to a session whose writes are recorded by the
+// returned scriptRecorder instead of being executed.
+func (g *Gormigrate) record(fn func(rec *scriptRecorder) error) (*scriptRecorder, error) {
+	rec := &scriptRecorder{ConnPool: g.db.Statement.ConnPool, dialector: g.db.Dialector}
+	g.tx = withConnPool(g.db, rec)
+	g.state = g.tx
+	defer func() {
+		g.tx = g.db
+		g.state = g.db
+	}()
+
+	if err := fn(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// writeSQL writes lines to w as an SQL script, terminating each statement
+// with a semicolon. Lines starting with "-- " are written as comments.
+func writeSQL(w io.Writer, lines []string) error {
+	for i, line := range lines {
+		var err error
+		if strings.HasPrefix(line, "-- ") {
+			if i > 0 {
+				line = "\n" + line
+			}
+			_, err = fmt.Fprintf(w, "%s\n", line)
+		} else {
+			_, err = fmt.Fprintf(w, "%s;\n", strings.TrimRight(line, "; \t\r\n"))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package gormigrate
+
+// MigrationState is the state of a migration, as reported by Status.
+type MigrationState string
+
+const (
+	// StatePending means the migration did not run yet, or is a repeatable
+	// migration whose checksum changed since it last ran.
+	StatePending MigrationState = "pending"
+	// StateApplied means the migration ran.
+	StateApplied MigrationState = "applied"
+	// StateSkippedByPrecondition means the migration was recorded as ran
+	// without running, its precondition having failed with
+	// PreconditionSkipAndMarkRan.
+	StateSkippedByPrecondition MigrationState = "skipped-by-precondition"
+	// StateSkippedByFilter means the migration is left out of runs by
+	// Options.IncludeTags or Options.ExcludeTags.
+	StateSkippedByFilter MigrationState = "skipped-by-filter"
+	// StatePreconditionFailed means the migration did not run yet and its
+	// precondition currently fails.
+	StatePreconditionFailed MigrationState = "precondition-failed"
+)
+
+// pending reports whether the migration in this state did not run yet.
+func (s MigrationState) pending() bool {
+	return s == StatePending || s == StatePreconditionFailed
+}
+
+// MigrationStatus is the status of a single migration.
+type MigrationStatus struct {
+	// ID is the migration identifier.
+	ID string
+	// State is the state of the migration.
+	State MigrationState
+	// Phase is the deploy phase of the migration.
+	Phase Phase
+}
+
+// Status returns the status of every migration, in the order they are defined.
+// It only reads from the database and does not create the migration table.
+// Preconditions of migrations that did not run yet are evaluated, so they
+// should only read from the database as well.
+func (g *Gormigrate) Status() ([]*MigrationStatus, error) {
+	statuses, err := g.recordedStatuses()
+	if err != nil {
+		return nil, err
+	}
+	for i, status := range statuses {
+		migration := g.migrations[i]
+		if status.State == StatePending && migration.Precondition != nil {
+			ok, err := migration.Precondition(g.tx)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				status.State = StatePreconditionFailed
+			}
+		}
+	}
+	g.observeStatuses(statuses)
+	return statuses, nil
+}
+
+// recordedStatuses returns the status of every migration as recorded by the
+// store, without evaluating preconditions.
+func (g *Gormigrate) recordedStatuses() ([]*MigrationStatus, error) {
+	g.tx = g.db
+	g.state = g.stateDatabase()
+	hasTable, err := g.stateStore().Exists(g.state)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*MigrationStatus, 0, len(g.migrations))
+	for _, migration := range g.migrations {
+		status := &MigrationStatus{ID: migration.ID, State: StatePending, Phase: migration.phase()}
+		statuses = append(statuses, status)
+
+		switch {
+		case !g.selected(migration):
+			status.State = StateSkippedByFilter
+		case !hasTable:
+		default:
+			applied, err := g.stateStore().Find(g.state, migration.ID)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case applied == nil || migration.Repeatable && applied.Checksum != migration.Checksum:
+			case applied.Skipped:
+				status.State = StateSkippedByPrecondition
+			default:
+				status.State = StateApplied
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// selected reports whether the migration is selected to run by
+// Options.IncludeTags and Options.ExcludeTags.
+func (g *Gormigrate) selected(m *Migration) bool {
+	if len(m.Tags) == 0 {
+		return true
+	}
+	if hasAnyTag(m.Tags, g.options.ExcludeTags) {
+		return false
+	}
+	return len(g.options.IncludeTags) == 0 || hasAnyTag(m.Tags, g.options.IncludeTags)
+}
+
+func hasAnyTag(tags, lookup []string) bool {
+	for _, tag := range tags {
+		for _, l := range lookup {
+			if tag == l {
+				return true
+			}
+		}
+	}
+	return false
+}
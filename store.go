@@ -0,0 +1,373 @@
+package gormigrate
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// AppliedMigration is a migration recorded as applied by a Store.
+type AppliedMigration struct {
+	// ID is the migration identifier.
+	ID string `json:"id"`
+	// Checksum is the checksum of a repeatable migration when it last ran.
+	Checksum string `json:"checksum,omitempty"`
+	// Skipped is true when the migration was recorded without running, its
+	// precondition having failed with PreconditionSkipAndMarkRan.
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// Store keeps track of the migrations that ran.
+//
+// Every method receives the state database, see UseStateDB, which is a
+// transaction when Options.UseTransaction is set. Stores keeping their state
+// elsewhere can ignore it, but their changes are then not rolled back with
+// the transaction.
+type Store interface {
+	// Exists reports whether the storage was created.
+	Exists(tx *gorm.DB) (bool, error)
+	// Ensure creates the storage if it does not exist yet.
+	Ensure(tx *gorm.DB) error
+	// List returns the applied migrations, in no particular order.
+	List(tx *gorm.DB) ([]*AppliedMigration, error)
+	// Find returns the applied migration with the given ID, or nil if it did not run.
+	Find(tx *gorm.DB, id string) (*AppliedMigration, error)
+	// Record records the migration as applied. A migration that ran again
+	// is removed before being recorded.
+	Record(tx *gorm.DB, m *AppliedMigration) error
+	// Remove removes the record of the migration with the given ID.
+	Remove(tx *gorm.DB, id string) error
+	// Lock prevents other runs from changing the migrations until the
+	// returned function is called.
+	Lock(tx *gorm.DB) (func() error, error)
+}
+
+// TableStore is the default Store, keeping the applied migrations in a table
+// of the state database.
+type TableStore struct {
+	// TableName is the migration table. It can be qualified by a schema, like
+	// "ops.migrations".
+	TableName string
+	// Schema is the schema of the migration table, overriding the one of
+	// TableName. On MySQL, schemas are databases, and on SQLite, attached databases.
+	Schema string
+	// CreateSchema creates the schema along with the migration table, on
+	// Postgres, MySQL and SQL Server.
+	CreateSchema bool
+	// IDColumnName is the name of column where the migration id will be stored.
+	IDColumnName string
+	// IDColumnSize is the length of the migration id column
+	IDColumnSize int
+	// AdvisoryLock makes Lock take an advisory lock named after the migration
+	// table on Postgres, MySQL and SQL Server. The lock is held on a
+	// connection of the pool during the whole run, which therefore needs at
+	// least two connections. Runs are not locked when false.
+	AdvisoryLock bool
+}
+
+// stateStore returns Options.Store, or the TableStore configured by the options.
+func (g *Gormigrate) stateStore() Store {
+	if g.options.Store != nil {
+		return g.options.Store
+	}
+	tableName := g.options.TableName
+	if g.options.UseTablePrefix {
+		tableName = tablePrefix(g.stateDatabase()) + tableName
+	}
+	return &TableStore{
+		TableName:    tableName,
+		Schema:       g.options.TableSchema,
+		CreateSchema: g.options.CreateTableSchema,
+		IDColumnName: g.options.IDColumnName,
+		IDColumnSize: g.options.IDColumnSize,
+		AdvisoryLock: g.options.AdvisoryLock,
+	}
+}
+
+// tablePrefix returns the table prefix of the naming strategy of db.
+func tablePrefix(db *gorm.DB) string {
+	switch namer := db.NamingStrategy.(type) {
+	case schema.NamingStrategy:
+		return namer.TablePrefix
+	case *schema.NamingStrategy:
+		return namer.TablePrefix
+	}
+	return ""
+}
+
+// names returns the schema of the migration table, empty when not qualified,
+// and its unqualified name.
+func (s *TableStore) names() (string, string) {
+	tableSchema, table := "", s.TableName
+	if i := strings.Index(table, "."); i >= 0 {
+		tableSchema, table = table[:i], table[i+1:]
+	}
+	if s.Schema != "" {
+		tableSchema = s.Schema
+	}
+	return tableSchema, table
+}
+
+// table returns the qualified name of the migration table.
+func (s *TableStore) table() string {
+	tableSchema, table := s.names()
+	if tableSchema == "" {
+		return table
+	}
+	return tableSchema + "." + table
+}
+
+// model returns pointer to dynamically created gorm migration model struct value
+//
+//	struct defined as {
+//	  ID       string `gorm:"primaryKey;column:<IDColumnName>;size:<IDColumnSize>"`
+//	  Checksum string `gorm:"column:checksum;size:255"`
+//	  Status   string `gorm:"column:status;size:32"`
+//	}
+func (s *TableStore) model() any {
+	f := reflect.StructField{
+		Name: reflect.ValueOf("ID").Interface().(string),
+		Type: reflect.TypeOf(""),
+		Tag: reflect.StructTag(fmt.Sprintf(
+			`gorm:"primaryKey;column:%s;size:%d"`,
+			s.IDColumnName,
+			s.IDColumnSize,
+		)),
+	}
+	checksum := reflect.StructField{
+		Name: "Checksum",
+		Type: reflect.TypeOf(""),
+		Tag:  `gorm:"column:checksum;size:255"`,
+	}
+	status := reflect.StructField{
+		Name: "Status",
+		Type: reflect.TypeOf(""),
+		Tag:  `gorm:"column:status;size:32"`,
+	}
+	structType := reflect.StructOf([]reflect.StructField{f, checksum, status})
+	structValue := reflect.New(structType).Elem()
+	return structValue.Addr().Interface()
+}
+
+// Exists implements Store.
+func (s *TableStore) Exists(tx *gorm.DB) (bool, error) {
+	tableSchema, table := s.names()
+	return hasTable(tx, tableSchema, table)
+}
+
+// hasTable reports whether the table exists in the schema, or in the current
+// schema when empty.
+func hasTable(tx *gorm.DB, tableSchema, table string) (bool, error) {
+	if tableSchema == "" {
+		return tx.Migrator().HasTable(table), nil
+	}
+	// The migrators of the dialects do not all support qualified names
+	query := "SELECT count(*) FROM information_schema.tables WHERE table_schema = ? AND table_name = ?"
+	args := []any{tableSchema, table}
+	if tx.Dialector.Name() == "sqlite" {
+		var databases int64
+		if err := tx.Raw("SELECT count(*) FROM pragma_database_list WHERE name = ?", tableSchema).Scan(&databases).Error; err != nil || databases == 0 {
+			return false, err
+		}
+		query = fmt.Sprintf("SELECT count(*) FROM %s.sqlite_master WHERE type = 'table' AND name = ?", tx.Statement.Quote(tableSchema))
+		args = []any{table}
+	}
+	var count int64
+	err := tx.Raw(query, args...).Scan(&count).Error
+	return count > 0, err
+}
+
+// hasColumn reports whether the migration table has the given column.
+func (s *TableStore) hasColumn(tx *gorm.DB, column string) (bool, error) {
+	tableSchema, table := s.names()
+	if tableSchema == "" {
+		return tx.Migrator().HasColumn(table, column), nil
+	}
+	query := "SELECT count(*) FROM information_schema.columns WHERE table_schema = ? AND table_name = ? AND column_name = ?"
+	args := []any{tableSchema, table, column}
+	if tx.Dialector.Name() == "sqlite" {
+		query = "SELECT count(*) FROM pragma_table_info(?, ?) WHERE name = ?"
+		args = []any{table, tableSchema, column}
+	}
+	var count int64
+	err := tx.Raw(query, args...).Scan(&count).Error
+	return count > 0, err
+}
+
+// createSchemaStatements create a schema if it does not exist, for each
+// dialect supporting it. The schema is passed quoted and as an argument.
+var createSchemaStatements = map[string]string{
+	"postgres":  "CREATE SCHEMA IF NOT EXISTS %[1]s",
+	"mysql":     "CREATE DATABASE IF NOT EXISTS %[1]s",
+	"sqlserver": "IF SCHEMA_ID(?) IS NULL EXEC('CREATE SCHEMA %[1]s')",
+}
+
+func (s *TableStore) createSchema(tx *gorm.DB) error {
+	tableSchema, _ := s.names()
+	if !s.CreateSchema || tableSchema == "" {
+		return nil
+	}
+	statement, ok := createSchemaStatements[tx.Dialector.Name()]
+	if !ok {
+		return ErrCreateSchemaUnsupported
+	}
+	statement = fmt.Sprintf(statement, tx.Statement.Quote(tableSchema))
+	if strings.Contains(statement, "?") {
+		return tx.Exec(statement, tableSchema).Error
+	}
+	return tx.Exec(statement).Error
+}
+
+// List implements Store. Migration tables of older layouts are read as they
+// are, so that reading does not require Ensure to upgrade them first.
+func (s *TableStore) List(tx *gorm.DB) ([]*AppliedMigration, error) {
+	layout, err := s.layout(tx)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]any
+	if err := tx.Table(s.table()).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	migrations := make([]*AppliedMigration, 0, len(rows))
+	for _, row := range rows {
+		migrations = append(migrations, appliedFromRow(row, layout.IDColumnName))
+	}
+	return migrations, nil
+}
+
+// Find implements Store. Like List, it reads migration tables of older layouts.
+func (s *TableStore) Find(tx *gorm.DB, id string) (*AppliedMigration, error) {
+	layout, err := s.layout(tx)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]any
+	err = tx.
+		Table(s.table()).
+		Where(fmt.Sprintf("%s = ?", layout.IDColumnName), id).
+		Limit(1).
+		Find(&rows).
+		Error
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return appliedFromRow(rows[0], layout.IDColumnName), nil
+}
+
+// appliedFromRow returns the applied migration of a row of the migration
+// table, whose columns depend on its layout.
+func appliedFromRow(row map[string]any, idColumn string) *AppliedMigration {
+	return &AppliedMigration{
+		ID:       columnString(row[idColumn]),
+		Checksum: columnString(row["checksum"]),
+		Skipped:  columnString(row["status"]) == statusSkipped,
+	}
+}
+
+// columnString returns the value of a text column, as scanned by the driver.
+func columnString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// Values of the status column of the migration table.
+const (
+	statusApplied = "applied"
+	statusSkipped = "skipped"
+)
+
+// Record implements Store.
+func (s *TableStore) Record(tx *gorm.DB, m *AppliedMigration) error {
+	status := statusApplied
+	if m.Skipped {
+		status = statusSkipped
+	}
+	record := s.model()
+	reflect.ValueOf(record).Elem().FieldByName("ID").SetString(m.ID)
+	reflect.ValueOf(record).Elem().FieldByName("Checksum").SetString(m.Checksum)
+	reflect.ValueOf(record).Elem().FieldByName("Status").SetString(status)
+	return insertInto(tx, s.table(), record)
+}
+
+// insertInto inserts value into the table, which can be qualified by a
+// schema. The sqlite dialector drops the schema of the table it inserts
+// into, unless set by an explicit INSERT clause.
+func insertInto(tx *gorm.DB, table string, value any) error {
+	return tx.Table(table).Clauses(clause.Insert{Table: clause.Table{Name: table}}).Create(value).Error
+}
+
+// Remove implements Store.
+func (s *TableStore) Remove(tx *gorm.DB, id string) error {
+	cond := fmt.Sprintf("%s = ?", s.IDColumnName)
+	return tx.Table(s.table()).Where(cond, id).Delete(s.model()).Error
+}
+
+// tableLocks hold the statements taking and releasing a session-level lock
+// named after the migration table, for each dialect supporting them.
+var tableLocks = map[string]struct{ lock, unlock string }{
+	"postgres": {
+		lock:   "SELECT pg_advisory_lock(?)",
+		unlock: "SELECT pg_advisory_unlock(?)",
+	},
+	"mysql": {
+		lock:   "SELECT GET_LOCK(?, -1)",
+		unlock: "SELECT RELEASE_LOCK(?)",
+	},
+	"sqlserver": {
+		lock:   "EXEC sp_getapplock @Resource = ?, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = -1",
+		unlock: "EXEC sp_releaseapplock @Resource = ?, @LockOwner = 'Session'",
+	},
+}
+
+// Lock implements Store. With AdvisoryLock, it takes an advisory lock named
+// after the migration table on Postgres, MySQL and SQL Server, through a
+// connection of its own which is held until unlocking. Other dialects are
+// not locked.
+func (s *TableStore) Lock(tx *gorm.DB) (func() error, error) {
+	statements, ok := tableLocks[tx.Dialector.Name()]
+	if !s.AdvisoryLock || !ok {
+		return func() error { return nil }, nil
+	}
+	sqlDB, err := tx.DB()
+	if err != nil {
+		return nil, err
+	}
+	if sqlDB.Stats().MaxOpenConnections == 1 {
+		return nil, ErrLockSingleConnection
+	}
+	session, conn, err := pinConnection(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var key any = fmt.Sprintf("gormigrate:%s", s.table())
+	if tx.Dialector.Name() == "postgres" {
+		h := fnv.New64a()
+		h.Write([]byte(key.(string)))
+		key = int64(h.Sum64())
+	}
+	if err := session.Exec(statements.lock, key).Error; err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return func() error {
+		err := session.Exec(statements.unlock, key).Error
+		if closeErr := conn.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
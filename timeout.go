@@ -0,0 +1,238 @@
+package gormigrate
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// timeoutSetting is a setting of the database session holding a timeout.
+type timeoutSetting struct {
+	// query returns the current value of the setting.
+	query string
+	// set changes the setting for the session, formatted with the value.
+	set string
+	// setLocal changes the setting until the end of the transaction. Empty
+	// when the dialect has no such statement.
+	setLocal string
+	// value formats the timeout for set and setLocal.
+	value func(time.Duration) string
+}
+
+// lockTimeoutSettings limit how long a statement waits for a lock, for each
+// dialect supporting it.
+var lockTimeoutSettings = map[string]timeoutSetting{
+	"postgres": {
+		query:    "SELECT current_setting('lock_timeout')",
+		set:      "SET lock_timeout = '%s'",
+		setLocal: "SET LOCAL lock_timeout = '%s'",
+		value:    milliseconds,
+	},
+	"mysql": {
+		query: "SELECT @@SESSION.innodb_lock_wait_timeout",
+		set:   "SET SESSION innodb_lock_wait_timeout = %s",
+		value: seconds,
+	},
+	"sqlserver": {
+		query: "SELECT @@LOCK_TIMEOUT",
+		set:   "SET LOCK_TIMEOUT %s",
+		value: milliseconds,
+	},
+	"sqlite": {
+		query: "PRAGMA busy_timeout",
+		set:   "PRAGMA busy_timeout = %s",
+		value: milliseconds,
+	},
+}
+
+// statementTimeoutSettings limit how long a statement runs, for each dialect
+// supporting it. MariaDB, whose dialect is "mysql" as well, has its own.
+var statementTimeoutSettings = map[string]timeoutSetting{
+	"postgres": {
+		query:    "SELECT current_setting('statement_timeout')",
+		set:      "SET statement_timeout = '%s'",
+		setLocal: "SET LOCAL statement_timeout = '%s'",
+		value:    milliseconds,
+	},
+	"mysql": {
+		query: "SELECT @@SESSION.max_execution_time",
+		set:   "SET SESSION max_execution_time = %s",
+		value: milliseconds,
+	},
+	"mariadb": {
+		query: "SELECT @@SESSION.max_statement_time",
+		set:   "SET SESSION max_statement_time = %s",
+		value: func(d time.Duration) string {
+			return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+		},
+	},
+}
+
+// milliseconds rounds d up to a whole number of milliseconds, since zero
+// usually disables the timeout.
+func milliseconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Millisecond-1)/time.Millisecond), 10)
+}
+
+// seconds rounds d up to a whole number of seconds.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
+
+// timeouts returns the lock and statement timeouts of the migration, which
+// default to the ones of the options.
+func (g *Gormigrate) timeouts(m *Migration) (time.Duration, time.Duration) {
+	lockTimeout, statementTimeout := g.options.LockTimeout, g.options.StatementTimeout
+	if m.LockTimeout != 0 {
+		lockTimeout = m.LockTimeout
+	}
+	if m.StatementTimeout != 0 {
+		statementTimeout = m.StatementTimeout
+	}
+	return lockTimeout, statementTimeout
+}
+
+// withTimeouts returns fn running with the lock and statement timeouts of the
+// migration, which are reset to their previous values afterwards. Without
+// Options.UseTransaction, fn runs on a single connection of the pool, so that
+// the timeouts apply to all its statements.
+func (g *Gormigrate) withTimeouts(m *Migration, fn func(*gorm.DB) error) func(*gorm.DB) error {
+	lockTimeout, statementTimeout := g.timeouts(m)
+	if lockTimeout <= 0 && statementTimeout <= 0 {
+		return fn
+	}
+	return func(tx *gorm.DB) (err error) {
+		var conn *sql.Conn
+		if !g.options.UseTransaction {
+			if tx, conn, err = pinConnection(tx); err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := conn.Close(); err == nil {
+					err = closeErr
+				}
+			}()
+		}
+
+		var resets []func(failed bool) error
+		reset := func(failed bool) error {
+			for i := len(resets) - 1; i >= 0; i-- {
+				if err := resets[i](failed); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		defer func() {
+			if resetErr := reset(err != nil); resetErr != nil {
+				if conn != nil {
+					// Do not give the connection back to the pool with the timeouts
+					_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+				}
+				if err == nil {
+					err = resetErr
+				} else {
+					tx.Logger.Error(contextOf(tx), "gormigrate: resetting the timeouts of migration %q failed: %v", m.ID, resetErr)
+				}
+			}
+		}()
+
+		dialect, err := timeoutDialect(tx)
+		if err != nil {
+			return err
+		}
+		for _, timeout := range []struct {
+			duration time.Duration
+			settings map[string]timeoutSetting
+		}{
+			{lockTimeout, lockTimeoutSettings},
+			{statementTimeout, statementTimeoutSettings},
+		} {
+			if timeout.duration <= 0 {
+				continue
+			}
+			setting, ok := timeout.settings[dialect]
+			if !ok {
+				setting, ok = timeout.settings[tx.Dialector.Name()]
+			}
+			if !ok {
+				return ErrTimeoutUnsupported
+			}
+			resetSetting, err := setting.apply(tx, timeout.duration, g.options.UseTransaction)
+			if err != nil {
+				return err
+			}
+			resets = append(resets, resetSetting)
+		}
+		return fn(tx)
+	}
+}
+
+// apply changes the setting to d, and returns the function resetting it.
+// Settings local to the transaction are not reset when the migration failed,
+// since the transaction is rolled back and may not accept statements anymore.
+func (s timeoutSetting) apply(tx *gorm.DB, d time.Duration, inTransaction bool) (func(failed bool) error, error) {
+	var previous string
+	if err := tx.Raw(s.query).Scan(&previous).Error; err != nil {
+		return nil, err
+	}
+	statement := s.set
+	local := inTransaction && s.setLocal != ""
+	if local {
+		statement = s.setLocal
+	}
+	if err := tx.Exec(fmt.Sprintf(statement, s.value(d))).Error; err != nil {
+		return nil, err
+	}
+	return func(failed bool) error {
+		if local && failed {
+			return nil
+		}
+		return tx.Exec(fmt.Sprintf(statement, strings.ReplaceAll(previous, "'", "''"))).Error
+	}, nil
+}
+
+// timeoutDialect returns the key of the timeout settings of the dialect of tx,
+// telling MariaDB apart from MySQL.
+func timeoutDialect(tx *gorm.DB) (string, error) {
+	dialect := tx.Dialector.Name()
+	if dialect != "mysql" {
+		return dialect, nil
+	}
+	var version string
+	if err := tx.Raw("SELECT VERSION()").Scan(&version).Error; err != nil {
+		return "", err
+	}
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		return "mariadb", nil
+	}
+	return dialect, nil
+}
+
+// pinConnection returns a session of tx running all its statements on a
+// single connection of the pool, which must be closed to give it back.
+func pinConnection(tx *gorm.DB) (*gorm.DB, *sql.Conn, error) {
+	sqlDB, err := tx.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := sqlDB.Conn(contextOf(tx))
+	if err != nil {
+		return nil, nil, err
+	}
+	return withConnPool(tx, conn), conn, nil
+}
+
+// withConnPool returns a session of tx running its statements on pool.
+func withConnPool(tx *gorm.DB, pool gorm.ConnPool) *gorm.DB {
+	// A context forces gorm to clone the statement, so replacing the
+	// connection pool does not affect tx.
+	session := tx.Session(&gorm.Session{Context: contextOf(tx)})
+	session.Statement.ConnPool = pool
+	return session
+}
@@ -0,0 +1,87 @@
+package gormigrate
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracerName = "github.com/go-gormigrate/gormigrate/v2"
+
+// Attributes of the spans.
+const (
+	// MigrationIDKey is the ID of the migration of a span, or the target ID of a run.
+	MigrationIDKey = attribute.Key("gormigrate.migration.id")
+	// StepKey is the step of the migration of a span, see Step.
+	StepKey = attribute.Key("gormigrate.step")
+	// PhaseKey is the deploy phase of a run of MigratePhase.
+	PhaseKey = attribute.Key("gormigrate.phase")
+	// DBSystemKey is the database system, as named by the OpenTelemetry semantic conventions.
+	DBSystemKey = attribute.Key("db.system")
+)
+
+// dbSystems maps the dialects whose name differs from the one of the
+// OpenTelemetry semantic conventions.
+var dbSystems = map[string]string{
+	"postgres":  "postgresql",
+	"sqlserver": "mssql",
+}
+
+func dbSystem(db *gorm.DB) attribute.KeyValue {
+	name := db.Dialector.Name()
+	if system, ok := dbSystems[name]; ok {
+		name = system
+	}
+	return DBSystemKey.String(name)
+}
+
+func contextOf(db *gorm.DB) context.Context {
+	if db.Statement.Context == nil {
+		return context.Background()
+	}
+	return db.Statement.Context
+}
+
+// startSpan starts a span as a child of the span of ctx. The span is not
+// recorded when Options.TracerProvider is nil.
+func (g *Gormigrate) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if g.options.TracerProvider == nil {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return g.options.TracerProvider.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// startRun starts the span of a call to Migrate, MigrateTo, MigratePhase or
+// one of the Rollback methods. Until the returned function ends the span with
+// the error of the call, the databases use the context of the span, so that
+// the queries of the run and the spans of its migrations are nested under it.
+// Ending the run also records the resulting state in Options.Metrics.
+func (g *Gormigrate) startRun(name string, attrs ...attribute.KeyValue) func(*error) {
+	if g.options.TracerProvider == nil {
+		return func(*error) {
+			g.observeState()
+		}
+	}
+	db, stateDB := g.db, g.stateDB
+	ctx, span := g.startSpan(contextOf(db), name, append(attrs, dbSystem(db))...)
+	g.db = db.WithContext(ctx)
+	if stateDB != nil {
+		g.stateDB = stateDB.WithContext(ctx)
+	}
+	return func(err *error) {
+		g.observeState()
+		g.db, g.stateDB = db, stateDB
+		endSpan(span, *err)
+	}
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,29 @@
+package gormigrate
+
+import "gorm.io/gorm"
+
+// runMigrate runs the Migrate function of the migration, or its backfill,
+// followed by its Verify function.
+func (g *Gormigrate) runMigrate(m *Migration) error {
+	migrate := MigrateFunc(func(tx *gorm.DB) error {
+		return g.runBackfill(m, tx)
+	})
+	if m.Backfill == nil {
+		var err error
+		if migrate, err = m.migrateFunc(g.tx.Dialector.Name()); err != nil {
+			return err
+		}
+		migrate = g.withTimeouts(m, migrate)
+	}
+	if err := g.run(m.ID, StepMigrate, migrate); err != nil {
+		return err
+	}
+	if m.Verify == nil {
+		return nil
+	}
+	if err := g.run(m.ID, StepVerify, m.Verify); err != nil {
+		g.tx.Logger.Error(g.tx.Statement.Context, "gormigrate: verification of migration %q failed: %v", m.ID, err)
+		return &VerificationFailedError{ID: m.ID, Err: err}
+	}
+	return nil
+}